@@ -0,0 +1,72 @@
+package simplegrpc
+
+import (
+	"context"
+
+	"github.com/bakins/simplegrpc/metadata"
+)
+
+// CallOption configures a unary call.
+type CallOption func(*callOptions)
+
+type callOptions struct {
+	header  *metadata.MD
+	trailer *metadata.MD
+}
+
+// Header returns a CallOption that retrieves the header metadata
+// for a unary RPC.
+func Header(md *metadata.MD) CallOption {
+	return func(o *callOptions) {
+		o.header = md
+	}
+}
+
+// Trailer returns a CallOption that retrieves the trailer metadata
+// for a unary RPC.
+func Trailer(md *metadata.MD) CallOption {
+	return func(o *callOptions) {
+		o.trailer = md
+	}
+}
+
+var unaryStreamDesc = StreamDesc{}
+
+// Invoke sends the RPC request on the wire and returns after response is
+// received. This is typically called by generated code.
+func (c *clientConn) Invoke(ctx context.Context, method string, args, reply interface{}, opts ...CallOption) error {
+	if c.unaryInterceptor == nil {
+		return invoke(ctx, method, args, reply, c, opts...)
+	}
+
+	return c.unaryInterceptor(ctx, method, args, reply, c, invoke, opts...)
+}
+
+func invoke(ctx context.Context, method string, args, reply interface{}, cc ClientConn, opts ...CallOption) error {
+	var o callOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	stream, err := cc.NewStream(ctx, &unaryStreamDesc, method)
+	if err != nil {
+		return err
+	}
+
+	// header and trailer are available even if the call fails
+	defer func() {
+		if o.header != nil {
+			*o.header, _ = stream.Header()
+		}
+
+		if o.trailer != nil {
+			*o.trailer = stream.Trailer()
+		}
+	}()
+
+	if err := stream.SendMsg(args); err != nil {
+		return err
+	}
+
+	return stream.RecvMsg(reply)
+}
@@ -5,7 +5,6 @@ import (
 	"context"
 	"crypto/tls"
 	"errors"
-	"fmt"
 	"io"
 	"io/ioutil"
 	"net"
@@ -13,23 +12,35 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/bakins/simplegrpc/codes"
+	"github.com/bakins/simplegrpc/metadata"
 	"github.com/bakins/simplegrpc/status"
 	"golang.org/x/net/http2"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/protobuf/proto"
 )
 
 // ClientConn ...
 type ClientConn interface {
+	// Invoke performs a unary RPC and returns after the response is received
+	// into reply.
+	Invoke(ctx context.Context, method string, args interface{}, reply interface{}, opts ...CallOption) error
+	// NewStream begins a streaming RPC.
 	NewStream(ctx context.Context, desc *StreamDesc, method string) (ClientStream, error)
 }
 
 type clientConn struct {
-	request     *http.Request
-	compressor  Compressor
-	codec       Codec
-	transport   http.RoundTripper
-	interceptor StreamClientInterceptor
+	request          *http.Request
+	compressor       Compressor
+	decompressors    map[string]Compressor
+	codec            Codec
+	transport        http.RoundTripper
+	interceptor      StreamClientInterceptor
+	unaryInterceptor UnaryClientInterceptor
+	maxRecvMsgSize   int
+	frameWriter      frameWriter
 }
 
 // TransportForEndpoint returns an HTTP/2 transport to be used with the endpoint
@@ -56,44 +67,103 @@ func TransportForEndpoint(endpoint string) (*http2.Transport, error) {
 type TransportWrapper func(http.RoundTripper) http.RoundTripper
 
 type Options struct {
-	transport   http.RoundTripper
-	wrapper     TransportWrapper
-	codec       Codec
-	compressor  Compressor
-	interceptor StreamClientInterceptor
+	transport               http.RoundTripper
+	wrapper                 TransportWrapper
+	codec                   Codec
+	compressor              Compressor
+	decompressors           []Compressor
+	streamInterceptor       StreamClientInterceptor
+	chainStreamInterceptors []StreamClientInterceptor
+	unaryInterceptor        UnaryClientInterceptor
+	chainUnaryInterceptors  []UnaryClientInterceptor
+	shared                  sharedOptions
+}
+
+// Option configures a ClientConn.
+type Option interface {
+	apply(*Options)
 }
 
-type Option func(*Options)
+type optionFunc func(*Options)
+
+func (f optionFunc) apply(o *Options) {
+	f(o)
+}
 
 // WithTransport sets the transport. No wrappers are called.
 // If no transport is set, TransportForEndpoint is used and the wrapper is called.
 // Note: http.DefaultTransport does not work with h2c and client streams may not work as expected with it.
 func WithTransport(transport http.RoundTripper) Option {
-	return func(o *Options) {
+	return optionFunc(func(o *Options) {
 		o.transport = transport
-	}
+	})
 }
 
 // WithTransportWrapper sets the transport wrapper. called to wrap the internal default transport.
 // Not called if WithTransport is used
 func WithTransportWrapper(wrapper TransportWrapper) Option {
-	return func(o *Options) {
+	return optionFunc(func(o *Options) {
 		o.wrapper = wrapper
-	}
+	})
 }
 
 // WithCodec sets the codec to use. Default is proto
 func WithCodec(codec Codec) Option {
-	return func(o *Options) {
+	return optionFunc(func(o *Options) {
 		o.codec = codec
-	}
+	})
 }
 
-// WithCompressor sets the compressor to use. There is no default
+// WithCompressor sets the compressor to use. There is no default.
+// It is also used to decompress responses.
 func WithCompressor(compressor Compressor) Option {
-	return func(o *Options) {
+	return optionFunc(func(o *Options) {
 		o.compressor = compressor
-	}
+	})
+}
+
+// WithDecompressor adds compressors used to decompress responses, in addition to those
+// registered with RegisterCompressor. They are advertised to the server in grpc-accept-encoding.
+// It may be used multiple times.
+func WithDecompressor(compressors ...Compressor) Option {
+	return optionFunc(func(o *Options) {
+		o.decompressors = append(o.decompressors, compressors...)
+	})
+}
+
+// WithStreamClientInterceptor sets the stream interceptor. It is run for every call,
+// including unary calls, before any interceptors added by WithChainStreamClientInterceptor.
+func WithStreamClientInterceptor(interceptor StreamClientInterceptor) Option {
+	return optionFunc(func(o *Options) {
+		o.streamInterceptor = interceptor
+	})
+}
+
+// WithChainStreamClientInterceptor adds stream interceptors. The first interceptor is
+// the outermost, and the last is the innermost wrapper around the real call.
+// It may be used multiple times.
+func WithChainStreamClientInterceptor(interceptors ...StreamClientInterceptor) Option {
+	return optionFunc(func(o *Options) {
+		o.chainStreamInterceptors = append(o.chainStreamInterceptors, interceptors...)
+	})
+}
+
+// WithUnaryClientInterceptor sets the unary interceptor. It is run for unary calls,
+// before any interceptors added by WithChainUnaryClientInterceptor and before the stream
+// for the call is created.
+func WithUnaryClientInterceptor(interceptor UnaryClientInterceptor) Option {
+	return optionFunc(func(o *Options) {
+		o.unaryInterceptor = interceptor
+	})
+}
+
+// WithChainUnaryClientInterceptor adds unary interceptors. The first interceptor is
+// the outermost, and the last is the innermost wrapper around the real call.
+// It may be used multiple times.
+func WithChainUnaryClientInterceptor(interceptors ...UnaryClientInterceptor) Option {
+	return optionFunc(func(o *Options) {
+		o.chainUnaryInterceptors = append(o.chainUnaryInterceptors, interceptors...)
+	})
 }
 
 // StreamClientInterceptor intercepts the creation of a ClientStream.
@@ -102,6 +172,13 @@ type StreamClientInterceptor func(ctx context.Context, desc *StreamDesc, cc Clie
 // Streamer is called by StreamClientInterceptor to create a ClientStream.
 type Streamer func(ctx context.Context, desc *StreamDesc, cc ClientConn, method string) (ClientStream, error)
 
+// UnaryClientInterceptor intercepts the execution of a unary RPC on the client. It is
+// the responsibility of the interceptor to call invoker to complete the RPC.
+type UnaryClientInterceptor func(ctx context.Context, method string, req, reply interface{}, cc ClientConn, invoker UnaryInvoker, opts ...CallOption) error
+
+// UnaryInvoker is called by UnaryClientInterceptor to complete RPCs.
+type UnaryInvoker func(ctx context.Context, method string, req, reply interface{}, cc ClientConn, opts ...CallOption) error
+
 // NewClientConn creates a new clientconn
 func NewClientConn(endpoint string, options ...Option) (ClientConn, error) {
 	request, err := http.NewRequest(http.MethodPost, endpoint, nil)
@@ -119,9 +196,11 @@ func NewClientConn(endpoint string, options ...Option) (ClientConn, error) {
 		request: request,
 	}
 
-	opts := Options{}
+	opts := Options{
+		shared: defaultSharedOptions(),
+	}
 	for _, o := range options {
-		o(&opts)
+		o.apply(&opts)
 	}
 
 	var transport http.RoundTripper
@@ -143,6 +222,20 @@ func NewClientConn(endpoint string, options ...Option) (ClientConn, error) {
 
 	c.transport = transport
 
+	streamInterceptors := opts.chainStreamInterceptors
+	if opts.streamInterceptor != nil {
+		streamInterceptors = append([]StreamClientInterceptor{opts.streamInterceptor}, streamInterceptors...)
+	}
+
+	c.interceptor = chainStreamClientInterceptors(streamInterceptors)
+
+	unaryInterceptors := opts.chainUnaryInterceptors
+	if opts.unaryInterceptor != nil {
+		unaryInterceptors = append([]UnaryClientInterceptor{opts.unaryInterceptor}, unaryInterceptors...)
+	}
+
+	c.unaryInterceptor = chainUnaryClientInterceptors(unaryInterceptors)
+
 	if opts.codec != nil {
 		c.codec = opts.codec
 	}
@@ -152,16 +245,61 @@ func NewClientConn(endpoint string, options ...Option) (ClientConn, error) {
 		c.request.Header.Set("Grpc-Encoding", c.compressor.Name())
 	}
 
+	c.decompressors = registeredCompressors()
+	for _, d := range opts.decompressors {
+		c.decompressors[d.Name()] = d
+	}
+
+	if c.compressor != nil {
+		c.decompressors[c.compressor.Name()] = c.compressor
+	}
+
+	if len(c.decompressors) > 0 {
+		c.request.Header.Set("Grpc-Accept-Encoding", acceptEncodingHeader(c.decompressors))
+	}
+
+	c.maxRecvMsgSize = opts.shared.maxRecvMsgSize
+	c.frameWriter = frameWriter{
+		codec:           c.codec,
+		compressor:      c.compressor,
+		maxSize:         opts.shared.maxSendMsgSize,
+		minCompressSize: opts.shared.compressionThreshold,
+	}
+
 	c.request.Header.Set("Content-Type", baseContentType+"+"+c.codec.Name())
 
 	return c, nil
 }
 
+// newFrameReader returns a reader for the response body. The response is
+// decompressed according to its grpc-encoding.
+func (c *clientConn) newFrameReader(resp *http.Response) frameReader {
+	encoding := resp.Header.Get("Grpc-Encoding")
+
+	return frameReader{
+		reader:     resp.Body,
+		codec:      c.codec,
+		encoding:   encoding,
+		compressor: c.decompressors[encoding],
+		maxSize:    c.maxRecvMsgSize,
+	}
+}
+
 // ClientStream ...
 type ClientStream interface {
 	Context() context.Context
 	SendMsg(m interface{}) error
 	RecvMsg(m interface{}) error
+	// CloseSend closes the send direction of the stream. It is a no-op
+	// for streams with a unary request.
+	CloseSend() error
+	// Header returns the header metadata received from the server. It blocks
+	// until the headers are received or the stream fails.
+	Header() (metadata.MD, error)
+	// Trailer returns the trailer metadata from the server. It must only be
+	// called after RecvMsg has returned a non-nil error (including io.EOF),
+	// or after the single response of a unary response stream was received.
+	Trailer() metadata.MD
 }
 
 // unary request streaming response
@@ -170,6 +308,8 @@ type unaryStreamRequest struct {
 	clientConn  *clientConn
 	request     *http.Request
 	response    *http.Response
+	reader      frameReader
+	err         error
 	requestSent chan struct{}
 }
 
@@ -178,11 +318,19 @@ type unaryUnaryRequest struct {
 	unaryStreamRequest
 }
 
-func (c *clientConn) NewStream(ctx context.Context, desc *StreamDesc, method string) (ClientStream, error) {
-	if desc.ClientStreams {
-		return nil, errors.New("client streams are not currently supported")
-	}
+// streaming request streaming response
+type streamStreamRequest struct {
+	unaryStreamRequest
+	writer    *io.PipeWriter
+	closeSent bool
+}
+
+// streaming request unary response
+type streamUnaryRequest struct {
+	*streamStreamRequest
+}
 
+func (c *clientConn) NewStream(ctx context.Context, desc *StreamDesc, method string) (ClientStream, error) {
 	// TODO: ensure resp body is closed always
 	if c.interceptor == nil {
 		return clientStreamer(ctx, desc, c, method)
@@ -200,6 +348,26 @@ func clientStreamer(ctx context.Context, desc *StreamDesc, cc ClientConn, method
 	request := c.request.Clone(ctx)
 	request.URL.Path = method
 
+	if deadline, ok := ctx.Deadline(); ok {
+		request.Header.Set("Grpc-Timeout", encodeTimeout(time.Until(deadline)))
+	}
+
+	if md, ok := metadata.FromOutgoingContext(ctx); ok {
+		setMetadataHeader(request.Header, md)
+	}
+
+	if desc.ClientStreams {
+		s := newStreamStreamRequest(ctx, c, request)
+
+		if desc.ServerStreams {
+			return s, nil
+		}
+
+		return &streamUnaryRequest{
+			streamStreamRequest: s,
+		}, nil
+	}
+
 	if desc.ServerStreams {
 		return &unaryStreamRequest{
 			ctx:         ctx,
@@ -230,6 +398,43 @@ func (u *unaryStreamRequest) Context() context.Context {
 	return u.ctx
 }
 
+func (u *unaryStreamRequest) CloseSend() error {
+	return nil
+}
+
+func (u *unaryStreamRequest) Header() (metadata.MD, error) {
+	select {
+	case <-u.ctx.Done():
+		return nil, status.FromContextError(u.ctx.Err()).Err()
+	case <-u.requestSent:
+	}
+
+	if u.response == nil {
+		if u.err != nil {
+			return nil, u.err
+		}
+
+		return nil, errors.New("no http response found")
+	}
+
+	return metadataFromHeader(u.response.Header)
+}
+
+func (u *unaryStreamRequest) Trailer() metadata.MD {
+	select {
+	case <-u.requestSent:
+	default:
+		return nil
+	}
+
+	if u.response == nil {
+		return nil
+	}
+
+	md, _ := metadataFromHeader(u.response.Trailer)
+	return md
+}
+
 func (u *unaryStreamRequest) closeRecv() {
 	_, _ = io.Copy(io.Discard, u.response.Body)
 	_ = u.response.Body.Close()
@@ -242,8 +447,10 @@ func (u *unaryStreamRequest) SendMsg(message interface{}) error {
 
 	defer close(u.requestSent)
 
+	// the transport may still be reading the body after the response arrives,
+	// so it is not taken from the buffer pool.
 	var buff bytes.Buffer
-	if err := sendMsg(&buff, u.clientConn.codec, u.clientConn.compressor, message); err != nil {
+	if err := u.clientConn.frameWriter.appendMsg(&buff, message); err != nil {
 		return err
 	}
 
@@ -255,16 +462,19 @@ func (u *unaryStreamRequest) SendMsg(message interface{}) error {
 
 	resp, err := client.Do(u.request)
 	if err != nil {
-		return err
+		return toRPCErr(u.ctx, err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		u.closeRecv()
-		return fmt.Errorf("unexpected http status: %d", resp.StatusCode)
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+
+		return httpStatusError(resp)
 	}
 
 	// TODO: check content type, compression
 	u.response = resp
+	u.reader = u.clientConn.newFrameReader(resp)
 
 	return nil
 }
@@ -272,36 +482,193 @@ func (u *unaryStreamRequest) SendMsg(message interface{}) error {
 func (u *unaryStreamRequest) RecvMsg(message interface{}) error {
 	select {
 	case <-u.ctx.Done():
-		return u.ctx.Err()
+		return status.FromContextError(u.ctx.Err()).Err()
 	case <-u.requestSent:
 	}
 
-	if u.request == nil {
+	if u.response == nil {
+		if u.err != nil {
+			return u.err
+		}
+
 		return errors.New("no http response found")
 	}
 
-	if err := recvMsg(u.response.Body, u.clientConn.codec, u.clientConn.compressor, message); err != nil {
+	if err := u.reader.readMsg(message); err != nil {
+		if _, ok := status.FromError(err); ok {
+			// the message was rejected locally, so do not wait for the server's status.
+			_ = u.response.Body.Close()
+			return err
+		}
+
 		u.closeRecv()
 
-		if code := getGrpcStatus(u.response); code != codes.OK {
-			msg := getGrpcMessage(u.response)
-			if msg == "" {
-				msg = code.String()
-			}
-			return status.Error(code, msg)
+		if st := responseStatus(u.response); st != nil {
+			return st.Err()
+		}
+
+		if err == io.EOF {
+			return err
 		}
 
+		return toRPCErr(u.ctx, err)
+	}
+
+	return nil
+}
+
+func newStreamStreamRequest(ctx context.Context, c *clientConn, request *http.Request) *streamStreamRequest {
+	reader, writer := io.Pipe()
+	request.Body = reader
+
+	s := &streamStreamRequest{
+		unaryStreamRequest: unaryStreamRequest{
+			ctx:         ctx,
+			clientConn:  c,
+			request:     request,
+			requestSent: make(chan struct{}),
+		},
+		writer: writer,
+	}
+
+	// the round trip only returns once the server has sent response headers,
+	// which may not happen until the server has read some or all of the request.
+	go s.do(reader)
+
+	return s
+}
+
+func (s *streamStreamRequest) do(reader *io.PipeReader) {
+	defer close(s.requestSent)
+
+	client := &http.Client{
+		Transport: s.clientConn.transport,
+	}
+
+	resp, err := client.Do(s.request)
+	if err != nil {
+		s.err = toRPCErr(s.ctx, err)
+		_ = reader.CloseWithError(s.err)
+		return
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+
+		s.err = httpStatusError(resp)
+		_ = reader.CloseWithError(s.err)
+		return
+	}
+
+	s.response = resp
+	s.reader = s.clientConn.newFrameReader(resp)
+}
+
+// SendMsg returns io.EOF if the stream was terminated by the server. The status can be
+// discovered using RecvMsg.
+func (s *streamStreamRequest) SendMsg(message interface{}) error {
+	if s.closeSent {
+		return errors.New("SendMsg called after CloseSend")
+	}
+
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	if err := s.clientConn.frameWriter.appendMsg(buf, message); err != nil {
 		return err
 	}
 
+	if _, err := s.writer.Write(buf.Bytes()); err != nil {
+		return io.EOF
+	}
+
 	return nil
 }
 
+func (s *streamStreamRequest) CloseSend() error {
+	if s.closeSent {
+		return nil
+	}
+
+	s.closeSent = true
+
+	return s.writer.Close()
+}
+
+// close body after receiving single message
+func (s *streamUnaryRequest) RecvMsg(message interface{}) error {
+	err := s.streamStreamRequest.RecvMsg(message)
+	if err == nil {
+		s.closeRecv()
+	}
+	return err
+}
+
+// toRPCErr converts an error from the HTTP transport into a status error.
+func toRPCErr(ctx context.Context, err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return status.FromContextError(ctxErr).Err()
+	}
+
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return status.FromContextError(err).Err()
+	}
+
+	return status.Error(codes.Unavailable, err.Error())
+}
+
+// httpStatusToCode maps HTTP status codes to codes as described in
+// https://github.com/grpc/grpc/blob/master/doc/http-grpc-status-mapping.md
+var httpStatusToCode = map[int]codes.Code{
+	http.StatusBadRequest:         codes.Internal,
+	http.StatusUnauthorized:       codes.Unauthenticated,
+	http.StatusForbidden:          codes.PermissionDenied,
+	http.StatusNotFound:           codes.Unimplemented,
+	http.StatusTooManyRequests:    codes.Unavailable,
+	http.StatusBadGateway:         codes.Unavailable,
+	http.StatusServiceUnavailable: codes.Unavailable,
+	http.StatusGatewayTimeout:     codes.Unavailable,
+}
+
+func httpStatusError(resp *http.Response) error {
+	code, ok := httpStatusToCode[resp.StatusCode]
+	if !ok {
+		code = codes.Unknown
+	}
+
+	return status.Errorf(code, "unexpected HTTP status code received from server: %d (%s)", resp.StatusCode, http.StatusText(resp.StatusCode))
+}
+
 var (
-	grpcStatus  = http.CanonicalHeaderKey("Grpc-Status")
-	grpcMessage = http.CanonicalHeaderKey("Grpc-Message")
+	grpcStatus        = http.CanonicalHeaderKey("Grpc-Status")
+	grpcMessage       = http.CanonicalHeaderKey("Grpc-Message")
+	grpcStatusDetails = http.CanonicalHeaderKey("Grpc-Status-Details-Bin")
 )
 
+// responseStatus returns the status sent by the server, or nil if the status is OK.
+func responseStatus(resp *http.Response) *status.Status {
+	code := getGrpcStatus(resp)
+	if code == codes.OK {
+		return nil
+	}
+
+	if st := getGrpcStatusDetails(resp); st != nil && codes.Code(st.GetCode()) == code {
+		return status.FromProto(st)
+	}
+
+	msg := getGrpcMessage(resp)
+	if msg == "" {
+		msg = code.String()
+	}
+
+	return status.New(code, msg)
+}
+
 func getGrpcStatus(resp *http.Response) codes.Code {
 	v := resp.Header.Get(grpcStatus)
 	if v == "0" {
@@ -327,9 +694,32 @@ func getGrpcStatus(resp *http.Response) codes.Code {
 
 func getGrpcMessage(resp *http.Response) string {
 	v := resp.Header.Get(grpcMessage)
-	if v != "" {
-		return v
+	if v == "" {
+		v = resp.Trailer.Get(grpcMessage)
+	}
+
+	return decodeGrpcMessage(v)
+}
+
+func getGrpcStatusDetails(resp *http.Response) *spb.Status {
+	v := resp.Header.Get(grpcStatusDetails)
+	if v == "" {
+		v = resp.Trailer.Get(grpcStatusDetails)
+	}
+
+	if v == "" {
+		return nil
+	}
+
+	data, err := decodeMetadataValue("grpc-status-details-bin", v)
+	if err != nil {
+		return nil
+	}
+
+	var st spb.Status
+	if err := proto.Unmarshal([]byte(data), &st); err != nil {
+		return nil
 	}
 
-	return resp.Trailer.Get(grpcMessage)
+	return &st
 }
@@ -20,18 +20,19 @@ package main
 
 import (
 	"fmt"
+	"path"
 	"strconv"
 	"strings"
 
+	"google.golang.org/genproto/googleapis/api/annotations"
 	"google.golang.org/protobuf/compiler/protogen"
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/descriptorpb"
 )
 
 const (
 	contextPackage = protogen.GoImportPath("context")
 	grpcPackage    = protogen.GoImportPath("github.com/bakins/simplegrpc")
-	codesPackage   = protogen.GoImportPath("github.com/bakins/simplegrpc/codes")
-	statusPackage  = protogen.GoImportPath("github.com/bakins/simplegrpc/status")
 	errorsPackage  = protogen.GoImportPath("errors")
 )
 
@@ -41,15 +42,34 @@ func generateFile(gen *protogen.Plugin, file *protogen.File) *protogen.Generated
 		return nil
 	}
 	filename := file.GeneratedFilenamePrefix + "_grpc_simple.pb.go"
-	g := gen.NewGeneratedFile(filename, file.GoImportPath)
+	importPath, packageName := file.GoImportPath, file.GoPackageName
+	if *simplePackage != "" {
+		// the messages are in another package, such as one generated by a third party,
+		// so the file is placed by its own import path.
+		importPath, packageName = parseSimplePackage(*simplePackage)
+		filename = path.Join(string(importPath), path.Base(file.GeneratedFilenamePrefix)) + "_grpc_simple.pb.go"
+	}
+	g := gen.NewGeneratedFile(filename, importPath)
 	g.P("// Code generated by protoc-gen-go-grpc-simple. DO NOT EDIT.")
 	g.P()
-	g.P("package ", file.GoPackageName)
+	g.P("package ", packageName)
 	g.P()
 	generateFileContent(gen, file, g)
 	return g
 }
 
+// parseSimplePackage parses the simple_package parameter, which is an import path
+// optionally followed by a semicolon and a package name, like the go_package option.
+func parseSimplePackage(s string) (protogen.GoImportPath, protogen.GoPackageName) {
+	if i := strings.Index(s, ";"); i >= 0 {
+		return protogen.GoImportPath(s[:i]), protogen.GoPackageName(s[i+1:])
+	}
+
+	name := strings.NewReplacer("-", "_", ".", "_").Replace(path.Base(s))
+
+	return protogen.GoImportPath(s), protogen.GoPackageName(name)
+}
+
 // generateFileContent generates the gRPC service definitions, excluding the package statement.
 func generateFileContent(gen *protogen.Plugin, file *protogen.File, g *protogen.GeneratedFile) {
 	if len(file.Services) == 0 {
@@ -170,9 +190,32 @@ func genService(gen *protogen.Plugin, file *protogen.File, g *protogen.Generated
 	for i, method := range service.Methods {
 		g.P("{")
 		g.P("StreamName: ", strconv.Quote(string(method.Desc.Name())), ",")
-		g.P("Handler: ", handlerNames[i], ",")
+		if !method.Desc.IsStreamingClient() && !method.Desc.IsStreamingServer() {
+			g.P("MethodHandler: ", handlerNames[i], ",")
+		} else {
+			g.P("Handler: ", handlerNames[i], ",")
+		}
 		g.P("ServerStreams:", strconv.FormatBool(method.Desc.IsStreamingServer()), ",")
 		g.P("ClientStreams:", strconv.FormatBool(method.Desc.IsStreamingClient()), ",")
+		if level := idempotencyLevel(method); level != "" {
+			g.P("IdempotencyLevel: ", grpcPackage.Ident(level), ",")
+		}
+		if rules := httpRules(method); len(rules) > 0 {
+			g.P("HTTPRules: []", grpcPackage.Ident("HTTPRule"), "{")
+			for _, rule := range rules {
+				g.P("{")
+				g.P("Method: ", strconv.Quote(rule.Method), ",")
+				g.P("Path: ", strconv.Quote(rule.Path), ",")
+				if rule.Body != "" {
+					g.P("Body: ", strconv.Quote(rule.Body), ",")
+				}
+				if rule.ResponseBody != "" {
+					g.P("ResponseBody: ", strconv.Quote(rule.ResponseBody), ",")
+				}
+				g.P("},")
+			}
+			g.P("},")
+		}
 		g.P("},")
 	}
 	g.P("},")
@@ -186,6 +229,9 @@ func clientSignature(g *protogen.GeneratedFile, method *protogen.Method) string
 	if !method.Desc.IsStreamingClient() {
 		s += ", in *" + g.QualifiedGoIdent(method.Input.GoIdent)
 	}
+	if !method.Desc.IsStreamingClient() && !method.Desc.IsStreamingServer() {
+		s += ", opts ..." + g.QualifiedGoIdent(grpcPackage.Ident("CallOption"))
+	}
 	s += ") ("
 	if !method.Desc.IsStreamingClient() && !method.Desc.IsStreamingServer() {
 		s += "*" + g.QualifiedGoIdent(method.Output.GoIdent)
@@ -208,11 +254,8 @@ func genClientMethod(gen *protogen.Plugin, file *protogen.File, g *protogen.Gene
 
 	g.P("func (c *", unexport(service.GoName), "SimpleClient) ", clientSignature(g, method), "{")
 	if !method.Desc.IsStreamingServer() && !method.Desc.IsStreamingClient() {
-		g.P("stream ,err := c.cc.NewStream(ctx, &", serviceDescVar, ".Streams[", index, `], "`, sname, `")`)
-		g.P("if err != nil { return nil, err }")
-		g.P("if err := stream.SendMsg(in); err != nil { return nil, err }")
 		g.P("var out ", method.Output.GoIdent)
-		g.P("if err := stream.RecvMsg(&out); err != nil { return nil, err }")
+		g.P(`if err := c.cc.Invoke(ctx, "`, sname, `", in, &out, opts...); err != nil { return nil, err }`)
 		g.P("return &out, nil")
 		g.P("}")
 		g.P()
@@ -221,24 +264,19 @@ func genClientMethod(gen *protogen.Plugin, file *protogen.File, g *protogen.Gene
 
 	streamType := unexport(service.GoName) + method.GoName + "SimpleClient"
 
-	if method.Desc.IsStreamingClient() {
-		g.P("return nil, ", statusPackage.Ident("New"), "(", codesPackage.Ident("Unimplemented"), `, "clients streams not currently supported")`)
-		g.P("}")
-		g.P()
-	} else {
-		g.P("stream, err := c.cc.NewStream(ctx, &", serviceDescVar, ".Streams[", index, `], "`, sname, `")`)
-		g.P("if err != nil { return nil, err }")
-		g.P("x := &", streamType, "{ClientStream: stream}")
-		if !method.Desc.IsStreamingClient() {
-			g.P("if err := x.ClientStream.SendMsg(in); err != nil { return nil, err }")
-		}
-		g.P("return x, nil")
-		g.P("}")
-		g.P()
+	g.P("stream, err := c.cc.NewStream(ctx, &", serviceDescVar, ".Streams[", index, `], "`, sname, `")`)
+	g.P("if err != nil { return nil, err }")
+	g.P("x := &", streamType, "{ClientStream: stream}")
+	if !method.Desc.IsStreamingClient() {
+		g.P("if err := x.ClientStream.SendMsg(in); err != nil { return nil, err }")
 	}
+	g.P("return x, nil")
+	g.P("}")
+	g.P()
 
 	genSend := method.Desc.IsStreamingClient()
 	genRecv := method.Desc.IsStreamingServer()
+	genCloseAndRecv := !method.Desc.IsStreamingServer()
 
 	// Stream auxiliary types and methods.
 	g.P("type ", service.GoName, "_", method.GoName, "SimpleClient interface {")
@@ -248,6 +286,9 @@ func genClientMethod(gen *protogen.Plugin, file *protogen.File, g *protogen.Gene
 	if genRecv {
 		g.P("Recv() (*", method.Output.GoIdent, ", error)")
 	}
+	if genCloseAndRecv {
+		g.P("CloseAndRecv() (*", method.Output.GoIdent, ", error)")
+	}
 	g.P(grpcPackage.Ident("ClientStream"))
 	g.P("}")
 	g.P()
@@ -271,6 +312,15 @@ func genClientMethod(gen *protogen.Plugin, file *protogen.File, g *protogen.Gene
 		g.P("}")
 		g.P()
 	}
+	if genCloseAndRecv {
+		g.P("func (x *", streamType, ") CloseAndRecv() (*", method.Output.GoIdent, ", error) {")
+		g.P("if err := x.ClientStream.CloseSend(); err != nil { return nil, err }")
+		g.P("var m ", method.Output.GoIdent)
+		g.P("if err := x.ClientStream.RecvMsg(&m); err != nil { return nil, err }")
+		g.P("return &m, nil")
+		g.P("}")
+		g.P()
+	}
 }
 
 func serverSignature(g *protogen.GeneratedFile, method *protogen.Method) string {
@@ -293,34 +343,41 @@ func genServerMethod(gen *protogen.Plugin, file *protogen.File, g *protogen.Gene
 	service := method.Parent
 	hname := fmt.Sprintf("_%s_%s_Simple_Handler", service.GoName, method.GoName)
 
-	g.P("func ", hname, "(srv interface{}, stream ", grpcPackage.Ident("ServerStream"), ") error {")
-
 	if !method.Desc.IsStreamingClient() && !method.Desc.IsStreamingServer() {
+		g.P("func ", hname, "(srv interface{}, ctx ", contextPackage.Ident("Context"), ", dec func(interface{}) error, interceptor ", grpcPackage.Ident("UnaryServerInterceptor"), ") (interface{}, error) {")
 		g.P("impl, ok := srv.(", service.GoName+"SimpleServer", ")")
 		g.P("if !ok {")
-		g.P("return ", errorsPackage.Ident("New"), `("invalid server type - expected `, service.GoName+"SimpleServer", `")`)
+		g.P("return nil, ", errorsPackage.Ident("New"), `("invalid server type - expected `, service.GoName+"SimpleServer", `")`)
+		g.P("}")
+		g.P("in := new(", method.Input.GoIdent, ")")
+		g.P("if err := dec(in); err != nil {")
+		g.P("return nil, err")
+		g.P("}")
+		g.P("if interceptor == nil {")
+		g.P("return impl.", method.GoName, "(ctx, in)")
 		g.P("}")
-		g.P("var in ", method.Input.GoIdent)
-		g.P("if err := stream.RecvMsg(&in); err != nil {")
-		g.P("return err")
+		g.P("info := &", grpcPackage.Ident("UnaryServerInfo"), "{")
+		g.P("Server: srv,")
+		g.P("FullMethod: ", strconv.Quote(fmt.Sprintf("/%s/%s", service.Desc.FullName(), method.Desc.Name())), ",")
 		g.P("}")
-		g.P("out, err := impl.", method.GoName, "(stream.Context(), &in)")
-		g.P("if err != nil {")
-		g.P("return err")
+		g.P("handler := func(ctx ", contextPackage.Ident("Context"), ", req interface{}) (interface{}, error) {")
+		g.P("return impl.", method.GoName, "(ctx, req.(*", method.Input.GoIdent, "))")
 		g.P("}")
-		g.P("return stream.SendMsg(out)")
+		g.P("return interceptor(ctx, in, info, handler)")
 		g.P("}")
 		g.P()
 		return hname
 	}
 
+	g.P("func ", hname, "(srv interface{}, stream ", grpcPackage.Ident("ServerStream"), ") error {")
+
 	streamType := unexport(service.GoName) + method.GoName + "Server"
 	if !method.Desc.IsStreamingClient() {
 		g.P("m := new(", method.Input.GoIdent, ")")
 		g.P("if err := stream.RecvMsg(m); err != nil { return err }")
 		g.P("return srv.(", service.GoName, "SimpleServer).", method.GoName, "(m, &", streamType, "{stream})")
 	} else {
-		g.P("return ", statusPackage.Ident("New"), "(", codesPackage.Ident("Unimplemented"), `, "clients streams not currently supported")`)
+		g.P("return srv.(", service.GoName, "SimpleServer).", method.GoName, "(&", streamType, "{stream})")
 	}
 	g.P("}")
 	g.P()
@@ -373,6 +430,71 @@ func genServerMethod(gen *protogen.Plugin, file *protogen.File, g *protogen.Gene
 	return hname
 }
 
+// idempotencyLevel returns the name of the IdempotencyLevel constant for the
+// method's idempotency_level option, or an empty string if it is not set.
+func idempotencyLevel(method *protogen.Method) string {
+	switch method.Desc.Options().(*descriptorpb.MethodOptions).GetIdempotencyLevel() {
+	case descriptorpb.MethodOptions_NO_SIDE_EFFECTS:
+		return "IdempotencyNoSideEffects"
+	case descriptorpb.MethodOptions_IDEMPOTENT:
+		return "IdempotencyIdempotent"
+	default:
+		return ""
+	}
+}
+
+// httpRule is a binding of a google.api.http annotation.
+type httpRule struct {
+	Method       string
+	Path         string
+	Body         string
+	ResponseBody string
+}
+
+// httpRules returns the bindings of the method's google.api.http option, including
+// additional bindings. Streaming methods can not be transcoded, so they have none.
+func httpRules(method *protogen.Method) []httpRule {
+	if method.Desc.IsStreamingClient() || method.Desc.IsStreamingServer() {
+		return nil
+	}
+
+	opts := method.Desc.Options().(*descriptorpb.MethodOptions)
+	if !proto.HasExtension(opts, annotations.E_Http) {
+		return nil
+	}
+
+	rule := proto.GetExtension(opts, annotations.E_Http).(*annotations.HttpRule)
+
+	var rules []httpRule
+	for _, r := range append([]*annotations.HttpRule{rule}, rule.GetAdditionalBindings()...) {
+		out := httpRule{
+			Body:         r.GetBody(),
+			ResponseBody: r.GetResponseBody(),
+		}
+
+		switch pattern := r.GetPattern().(type) {
+		case *annotations.HttpRule_Get:
+			out.Method, out.Path = "GET", pattern.Get
+		case *annotations.HttpRule_Put:
+			out.Method, out.Path = "PUT", pattern.Put
+		case *annotations.HttpRule_Post:
+			out.Method, out.Path = "POST", pattern.Post
+		case *annotations.HttpRule_Delete:
+			out.Method, out.Path = "DELETE", pattern.Delete
+		case *annotations.HttpRule_Patch:
+			out.Method, out.Path = "PATCH", pattern.Patch
+		case *annotations.HttpRule_Custom:
+			out.Method, out.Path = pattern.Custom.GetKind(), pattern.Custom.GetPath()
+		default:
+			continue
+		}
+
+		rules = append(rules, out)
+	}
+
+	return rules
+}
+
 const deprecationComment = "// Deprecated: Do not use."
 
 func unexport(s string) string { return strings.ToLower(s[:1]) + s[1:] }
@@ -40,7 +40,10 @@ import (
 
 const version = "0.1.0"
 
-var requireUnimplemented *bool
+var (
+	requireUnimplemented *bool
+	simplePackage        *string
+)
 
 func main() {
 	showVersion := flag.Bool("version", false, "print the version and exit")
@@ -51,6 +54,7 @@ func main() {
 	}
 
 	var flags flag.FlagSet
+	simplePackage = flags.String("simple_package", "", "import path and package name, separated by a semicolon, of the generated code, when it is not the package of the messages")
 
 	protogen.Options{
 		ParamFunc: flags.Set,
@@ -1,6 +1,14 @@
 package simplegrpc
 
-import "github.com/golang/protobuf/proto"
+import (
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/runtime/protoiface"
+	"google.golang.org/protobuf/runtime/protoimpl"
+
+	"github.com/bakins/simplegrpc/codes"
+	"github.com/bakins/simplegrpc/status"
+)
 
 type Codec interface {
 	Name() string
@@ -8,18 +16,180 @@ type Codec interface {
 	Unmarshal(data []byte, v interface{}) error
 }
 
+// NoRetainCodec is implemented by codecs whose Unmarshal does not retain data after
+// it returns. Messages are unmarshaled from reused buffers only by such codecs, other
+// codecs are given a copy of each message. The built-in codecs implement it.
+type NoRetainCodec interface {
+	Codec
+	// UnmarshalDoesNotRetain marks the codec, it is never called.
+	UnmarshalDoesNotRetain()
+}
+
+// unmarshal unmarshals data, which is in a reused buffer, copying it for codecs
+// that may retain it.
+func unmarshal(codec Codec, data []byte, v interface{}) error {
+	if _, ok := codec.(NoRetainCodec); !ok {
+		data = append([]byte(nil), data...)
+	}
+
+	return codec.Unmarshal(data, v)
+}
+
+// appendMarshaler is implemented by codecs that can marshal into an existing buffer.
+type appendMarshaler interface {
+	marshalAppend(b []byte, v interface{}) ([]byte, error)
+}
+
+// vtMarshaler and vtUnmarshaler are implemented by messages with generated
+// fast-path methods, such as those generated by vtprotobuf.
+type vtMarshaler interface {
+	MarshalVT() ([]byte, error)
+}
+
+// vtSizedMarshaler is implemented by vtprotobuf messages, which can marshal into
+// a buffer of their exact size.
+type vtSizedMarshaler interface {
+	SizeVT() int
+	MarshalToSizedBufferVT(dAtA []byte) (int, error)
+}
+
+type vtUnmarshaler interface {
+	UnmarshalVT([]byte) error
+}
+
+// ProtoCodec encodes messages as protobuf, using the content type application/grpc+proto.
 var ProtoCodec Codec = protoCodec{}
 
-type protoCodec struct{}
+// NewProtoCodec returns a protobuf codec that uses the given options.
+// The options are not used for messages that implement MarshalVT and UnmarshalVT.
+func NewProtoCodec(marshal proto.MarshalOptions, unmarshal proto.UnmarshalOptions) Codec {
+	return protoCodec{
+		marshal:   marshal,
+		unmarshal: unmarshal,
+	}
+}
+
+type protoCodec struct {
+	marshal   proto.MarshalOptions
+	unmarshal proto.UnmarshalOptions
+}
+
+func (p protoCodec) UnmarshalDoesNotRetain() {}
 
 func (p protoCodec) Name() string {
 	return "proto"
 }
 
 func (p protoCodec) Marshal(v interface{}) ([]byte, error) {
-	return proto.Marshal(v.(proto.Message))
+	return p.marshalAppend(nil, v)
+}
+
+func (p protoCodec) marshalAppend(b []byte, v interface{}) ([]byte, error) {
+	if vt, ok := v.(vtSizedMarshaler); ok {
+		size := vt.SizeVT()
+		b = grow(b, size)
+
+		// the message is marshaled from the end of the buffer.
+		if _, err := vt.MarshalToSizedBufferVT(b[len(b) : len(b)+size]); err != nil {
+			return nil, err
+		}
+
+		return b[:len(b)+size], nil
+	}
+
+	if vt, ok := v.(vtMarshaler); ok {
+		data, err := vt.MarshalVT()
+		if err != nil {
+			return nil, err
+		}
+
+		return append(b, data...), nil
+	}
+
+	m, ok := messageV2Of(v)
+	if !ok {
+		return nil, status.Errorf(codes.Internal, "failed to marshal, message is %T, want proto.Message", v)
+	}
+
+	return p.marshal.MarshalAppend(b, m)
 }
 
 func (p protoCodec) Unmarshal(data []byte, v interface{}) error {
-	return proto.Unmarshal(data, v.(proto.Message))
+	if vt, ok := v.(vtUnmarshaler); ok {
+		return vt.UnmarshalVT(data)
+	}
+
+	m, ok := messageV2Of(v)
+	if !ok {
+		return status.Errorf(codes.Internal, "failed to unmarshal, message is %T, want proto.Message", v)
+	}
+
+	return p.unmarshal.Unmarshal(data, m)
+}
+
+// grow returns b with capacity for at least n more bytes.
+func grow(b []byte, n int) []byte {
+	if cap(b)-len(b) >= n {
+		return b
+	}
+
+	out := make([]byte, len(b), len(b)+n)
+	copy(out, b)
+
+	return out
+}
+
+// messageV2Of returns v as a proto.Message, converting messages generated
+// for github.com/golang/protobuf.
+func messageV2Of(v interface{}) (proto.Message, bool) {
+	switch m := v.(type) {
+	case proto.Message:
+		return m, true
+	case protoiface.MessageV1:
+		return protoimpl.X.ProtoMessageV2Of(m), true
+	default:
+		return nil, false
+	}
+}
+
+// JSONCodec encodes messages as JSON, using the content type application/grpc+json.
+var JSONCodec Codec = jsonCodec{}
+
+// NewJSONCodec returns a JSON codec that uses the given options, such as
+// EmitUnpopulated and UseProtoNames for marshaling and DiscardUnknown for
+// unmarshaling.
+func NewJSONCodec(marshal protojson.MarshalOptions, unmarshal protojson.UnmarshalOptions) Codec {
+	return jsonCodec{
+		marshal:   marshal,
+		unmarshal: unmarshal,
+	}
+}
+
+type jsonCodec struct {
+	marshal   protojson.MarshalOptions
+	unmarshal protojson.UnmarshalOptions
+}
+
+func (j jsonCodec) UnmarshalDoesNotRetain() {}
+
+func (j jsonCodec) Name() string {
+	return "json"
+}
+
+func (j jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	m, ok := messageV2Of(v)
+	if !ok {
+		return nil, status.Errorf(codes.Internal, "failed to marshal, message is %T, want proto.Message", v)
+	}
+
+	return j.marshal.Marshal(m)
+}
+
+func (j jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	m, ok := messageV2Of(v)
+	if !ok {
+		return status.Errorf(codes.Internal, "failed to unmarshal, message is %T, want proto.Message", v)
+	}
+
+	return j.unmarshal.Unmarshal(data, m)
 }
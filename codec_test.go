@@ -0,0 +1,187 @@
+package simplegrpc
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/apipb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/bakins/simplegrpc/codes"
+	"github.com/bakins/simplegrpc/status"
+)
+
+func TestJSONCodec(t *testing.T) {
+	data, err := JSONCodec.Marshal(&apipb.Method{Name: "test", RequestTypeUrl: "type"})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"name":"test","requestTypeUrl":"type"}`, string(data))
+
+	var m apipb.Method
+	require.NoError(t, JSONCodec.Unmarshal(data, &m))
+	require.Equal(t, "type", m.RequestTypeUrl)
+
+	require.Error(t, JSONCodec.Unmarshal([]byte(`{"name":"test","unknown":true}`), &m))
+
+	_, err = JSONCodec.Marshal("test")
+	require.Equal(t, codes.Internal, status.Code(err))
+}
+
+func TestJSONCodecOptions(t *testing.T) {
+	codec := NewJSONCodec(
+		protojson.MarshalOptions{EmitUnpopulated: true, UseProtoNames: true},
+		protojson.UnmarshalOptions{DiscardUnknown: true},
+	)
+
+	data, err := codec.Marshal(&apipb.Method{Name: "test"})
+	require.NoError(t, err)
+	require.Contains(t, string(data), `"request_type_url":""`)
+	require.Contains(t, string(data), `"request_streaming":false`)
+
+	var m apipb.Method
+	require.NoError(t, codec.Unmarshal([]byte(`{"name":"test","unknown":true}`), &m))
+	require.Equal(t, "test", m.Name)
+}
+
+func TestProtoCodec(t *testing.T) {
+	data, err := ProtoCodec.Marshal(wrapperspb.String("hello"))
+	require.NoError(t, err)
+
+	var out wrapperspb.StringValue
+	require.NoError(t, ProtoCodec.Unmarshal(data, &out))
+	require.Equal(t, "hello", out.Value)
+
+	// generated code may implement only the github.com/golang/protobuf interface
+	var legacy legacyMessage
+	require.NoError(t, ProtoCodec.Unmarshal(data, &legacy))
+	require.Equal(t, "hello", legacy.Value)
+}
+
+func TestProtoCodecWrongType(t *testing.T) {
+	_, err := ProtoCodec.Marshal("test")
+	require.Equal(t, codes.Internal, status.Code(err))
+
+	var s string
+	err = ProtoCodec.Unmarshal(nil, &s)
+	require.Equal(t, codes.Internal, status.Code(err))
+}
+
+func TestProtoCodecOptions(t *testing.T) {
+	codec := NewProtoCodec(proto.MarshalOptions{Deterministic: true}, proto.UnmarshalOptions{})
+
+	s, err := structpb.NewStruct(map[string]interface{}{"a": 1, "b": 2, "c": 3, "d": 4})
+	require.NoError(t, err)
+
+	expected, err := codec.Marshal(s)
+	require.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		data, err := codec.Marshal(s)
+		require.NoError(t, err)
+		require.Equal(t, expected, data)
+	}
+}
+
+// vtMessage implements the fast-path methods.
+type vtMessage struct {
+	value string
+}
+
+func (v *vtMessage) MarshalVT() ([]byte, error) {
+	return []byte(v.value), nil
+}
+
+func (v *vtMessage) SizeVT() int {
+	return len(v.value)
+}
+
+func (v *vtMessage) MarshalToSizedBufferVT(data []byte) (int, error) {
+	i := len(data) - len(v.value)
+	copy(data[i:], v.value)
+
+	return len(v.value), nil
+}
+
+func (v *vtMessage) UnmarshalVT(data []byte) error {
+	v.value = string(data)
+	return nil
+}
+
+func TestProtoCodecVT(t *testing.T) {
+	data, err := ProtoCodec.Marshal(&vtMessage{value: "hello"})
+	require.NoError(t, err)
+	require.Equal(t, "hello", string(data))
+
+	var out vtMessage
+	require.NoError(t, ProtoCodec.Unmarshal(data, &out))
+	require.Equal(t, "hello", out.value)
+
+	// messages are marshaled into the buffer when it has capacity
+	buf := make([]byte, 2, 16)
+	copy(buf, "> ")
+
+	data, err = ProtoCodec.(appendMarshaler).marshalAppend(buf, &vtMessage{value: "hello"})
+	require.NoError(t, err)
+	require.Equal(t, "> hello", string(data))
+	require.Same(t, &buf[0], &data[0])
+
+	data, err = ProtoCodec.(appendMarshaler).marshalAppend(buf[:2:4], &vtMessage{value: "hello"})
+	require.NoError(t, err)
+	require.Equal(t, "> hello", string(data))
+}
+
+// legacyMessage only implements the github.com/golang/protobuf interface.
+type legacyMessage struct {
+	Value string `protobuf:"bytes,1,opt,name=value,proto3"`
+}
+
+func (m *legacyMessage) Reset()         { *m = legacyMessage{} }
+func (m *legacyMessage) String() string { return m.Value }
+func (m *legacyMessage) ProtoMessage()  {}
+
+// retainingCodec keeps the data of the last message it unmarshaled.
+type retainingCodec struct {
+	Codec
+	data *[]byte
+}
+
+func (r retainingCodec) Unmarshal(data []byte, v interface{}) error {
+	*r.data = data
+	return r.Codec.Unmarshal(data, v)
+}
+
+func TestUnmarshalRetain(t *testing.T) {
+	w := frameWriter{codec: ProtoCodec, maxSize: defaultMaxSendMsgSize}
+
+	var buf bytes.Buffer
+	require.NoError(t, w.writeMsg(&buf, wrapperspb.String("hello")))
+	require.NoError(t, w.writeMsg(&buf, wrapperspb.String("world")))
+
+	var retained []byte
+
+	r := frameReader{
+		reader:  &buf,
+		codec:   retainingCodec{Codec: ProtoCodec, data: &retained},
+		maxSize: defaultMaxRecvMsgSize,
+	}
+
+	var msg wrapperspb.StringValue
+	require.NoError(t, r.readMsg(&msg))
+
+	first := retained
+	require.NoError(t, r.readMsg(&msg))
+
+	// the codec may retain data, so it is not given the reused buffer.
+	var out wrapperspb.StringValue
+	require.NoError(t, ProtoCodec.Unmarshal(first, &out))
+	require.Equal(t, "hello", out.GetValue())
+
+	_, ok := ProtoCodec.(NoRetainCodec)
+	require.True(t, ok)
+
+	_, ok = JSONCodec.(NoRetainCodec)
+	require.True(t, ok)
+}
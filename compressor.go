@@ -3,6 +3,8 @@ package simplegrpc
 import (
 	"bytes"
 	"compress/gzip"
+	"compress/zlib"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"sync"
@@ -14,61 +16,248 @@ type Compressor interface {
 	Decompress([]byte) ([]byte, error)
 }
 
-var GzipCompressor Compressor = gzipCompressor{}
+// StreamCompressor compresses messages as they are written and read, rather than
+// materializing each stage of a message in memory. A Compressor that also implements
+// StreamCompressor is used as a StreamCompressor.
+type StreamCompressor interface {
+	Name() string
+	// NewWriter returns a writer that compresses data written to it into w.
+	// The compressed data is complete once the writer is closed.
+	NewWriter(w io.Writer) (io.WriteCloser, error)
+	// NewReader returns a reader that decompresses data read from r. If the reader
+	// implements io.Closer, it is closed once the message has been read.
+	NewReader(r io.Reader) (io.Reader, error)
+}
+
+// CompressorFromStream adapts a StreamCompressor to a Compressor.
+func CompressorFromStream(c StreamCompressor) Compressor {
+	return streamCompressor{c}
+}
+
+type streamCompressor struct {
+	StreamCompressor
+}
+
+func (s streamCompressor) Compress(in []byte) ([]byte, error) {
+	var buf bytes.Buffer
+
+	w, err := s.NewWriter(&buf)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := w.Write(in); err != nil {
+		_ = w.Close()
+		return nil, err
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (s streamCompressor) Decompress(in []byte) ([]byte, error) {
+	return decompressLimit(s, in, maxReceiveMessageSize+1)
+}
+
+// decompressLimit decompresses in, reading at most limit bytes of the decompressed data.
+func decompressLimit(sc StreamCompressor, in []byte, limit int64) ([]byte, error) {
+	r, err := sc.NewReader(bytes.NewReader(in))
+	if err != nil {
+		return nil, err
+	}
 
-var gzipWriterPool = sync.Pool{
-	New: func() interface{} {
-		return gzip.NewWriter(ioutil.Discard)
-	},
+	if c, ok := r.(io.Closer); ok {
+		defer c.Close()
+	}
+
+	return ioutil.ReadAll(io.LimitReader(r, limit))
+}
+
+// GzipCompressor compresses with gzip at the default level.
+var GzipCompressor = CompressorFromStream(newGzipCompressor(gzip.DefaultCompression))
+
+// NewGzipCompressor returns a gzip compressor that uses the given level.
+// See compress/gzip for the valid levels.
+func NewGzipCompressor(level int) (Compressor, error) {
+	if level < gzip.HuffmanOnly || level > gzip.BestCompression {
+		return nil, fmt.Errorf("invalid gzip compression level %d", level)
+	}
+
+	return CompressorFromStream(newGzipCompressor(level)), nil
 }
 
+// gzip readers do not depend on the level, so they are shared by all gzip compressors.
 var gzipReaderPool = sync.Pool{}
 
-type gzipCompressor struct{}
+type gzipCompressor struct {
+	writers *sync.Pool
+}
+
+func newGzipCompressor(level int) gzipCompressor {
+	g := gzipCompressor{
+		writers: &sync.Pool{},
+	}
+
+	g.writers.New = func() interface{} {
+		// the level has already been validated
+		z, _ := gzip.NewWriterLevel(ioutil.Discard, level)
+
+		return &gzipWriter{
+			Writer: z,
+			pool:   g.writers,
+		}
+	}
+
+	return g
+}
 
 func (g gzipCompressor) Name() string {
 	return "gzip"
 }
 
-func (g gzipCompressor) Compress(in []byte) ([]byte, error) {
-	var w bytes.Buffer
+func (g gzipCompressor) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	z := g.writers.Get().(*gzipWriter)
+	z.Reset(w)
 
-	z := gzipWriterPool.Get().(*gzip.Writer)
-	z.Reset(&w)
-	defer gzipWriterPool.Put(z)
+	return z, nil
+}
 
-	if _, err := z.Write(in); err != nil {
-		return nil, err
+func (g gzipCompressor) NewReader(r io.Reader) (io.Reader, error) {
+	z, ok := gzipReaderPool.Get().(*gzipReader)
+	if !ok {
+		n, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+
+		return &gzipReader{Reader: n}, nil
 	}
 
-	if err := z.Close(); err != nil {
+	if err := z.Reset(r); err != nil {
+		gzipReaderPool.Put(z)
 		return nil, err
 	}
 
-	return w.Bytes(), nil
+	return z, nil
+}
+
+// gzipWriter returns itself to its pool when closed.
+type gzipWriter struct {
+	*gzip.Writer
+	pool *sync.Pool
+}
+
+func (z *gzipWriter) Close() error {
+	err := z.Writer.Close()
+	z.pool.Put(z)
+
+	return err
+}
+
+// gzipReader returns itself to the pool when closed.
+type gzipReader struct {
+	*gzip.Reader
+}
+
+func (z *gzipReader) Close() error {
+	gzipReaderPool.Put(z)
+
+	return nil
+}
+
+// DeflateCompressor compresses with deflate at the default level. As in HTTP,
+// deflate is the zlib format.
+var DeflateCompressor = CompressorFromStream(newDeflateCompressor(zlib.DefaultCompression))
+
+// NewDeflateCompressor returns a deflate compressor that uses the given level.
+// See compress/zlib for the valid levels.
+func NewDeflateCompressor(level int) (Compressor, error) {
+	if level < zlib.HuffmanOnly || level > zlib.BestCompression {
+		return nil, fmt.Errorf("invalid deflate compression level %d", level)
+	}
+
+	return CompressorFromStream(newDeflateCompressor(level)), nil
+}
+
+var deflateReaderPool = sync.Pool{}
+
+type deflateCompressor struct {
+	writers *sync.Pool
+}
+
+func newDeflateCompressor(level int) deflateCompressor {
+	d := deflateCompressor{
+		writers: &sync.Pool{},
+	}
+
+	d.writers.New = func() interface{} {
+		// the level has already been validated
+		z, _ := zlib.NewWriterLevel(ioutil.Discard, level)
+
+		return &deflateWriter{
+			Writer: z,
+			pool:   d.writers,
+		}
+	}
+
+	return d
+}
+
+func (d deflateCompressor) Name() string {
+	return "deflate"
 }
 
-func (g gzipCompressor) Decompress(in []byte) ([]byte, error) {
-	r := bytes.NewReader(in)
+func (d deflateCompressor) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	z := d.writers.Get().(*deflateWriter)
+	z.Reset(w)
 
-	z, ok := gzipReaderPool.Get().(*gzip.Reader)
+	return z, nil
+}
+
+func (d deflateCompressor) NewReader(r io.Reader) (io.Reader, error) {
+	z, ok := deflateReaderPool.Get().(*deflateReader)
 	if !ok {
-		n, err := gzip.NewReader(r)
+		n, err := zlib.NewReader(r)
 		if err != nil {
 			return nil, err
 		}
 
-		z = n
-		defer gzipReaderPool.Put(z)
-	} else {
-		defer gzipReaderPool.Put(z)
+		return &deflateReader{ReadCloser: n}, nil
+	}
 
-		if err := z.Reset(r); err != nil {
-			return nil, err
-		}
+	if err := z.ReadCloser.(zlib.Resetter).Reset(r, nil); err != nil {
+		deflateReaderPool.Put(z)
+		return nil, err
 	}
 
-	return ioutil.ReadAll(io.LimitReader(z, int64(maxReceiveMessageSize)+1))
+	return z, nil
+}
+
+// deflateWriter returns itself to its pool when closed.
+type deflateWriter struct {
+	*zlib.Writer
+	pool *sync.Pool
+}
+
+func (z *deflateWriter) Close() error {
+	err := z.Writer.Close()
+	z.pool.Put(z)
+
+	return err
+}
+
+// deflateReader returns itself to the pool when closed.
+type deflateReader struct {
+	io.ReadCloser
+}
+
+func (z *deflateReader) Close() error {
+	deflateReaderPool.Put(z)
+
+	return nil
 }
 
 /*
@@ -0,0 +1,353 @@
+package simplegrpc
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bakins/simplegrpc/codes"
+	"github.com/bakins/simplegrpc/status"
+)
+
+// The Connect protocol is described in https://connectrpc.com/docs/protocol
+// Unary requests and responses are plain HTTP bodies, and streams use the
+// same framing as gRPC, ending with a JSON end-of-stream message.
+
+const (
+	connectUnaryContentTypePrefix  = "application/"
+	connectStreamContentTypePrefix = "application/connect+"
+)
+
+// connectCodeToHTTP maps codes to HTTP status codes for unary responses.
+var connectCodeToHTTP = map[codes.Code]int{
+	codes.Canceled:           499,
+	codes.Unknown:            http.StatusInternalServerError,
+	codes.InvalidArgument:    http.StatusBadRequest,
+	codes.DeadlineExceeded:   http.StatusGatewayTimeout,
+	codes.NotFound:           http.StatusNotFound,
+	codes.AlreadyExists:      http.StatusConflict,
+	codes.PermissionDenied:   http.StatusForbidden,
+	codes.ResourceExhausted:  http.StatusTooManyRequests,
+	codes.FailedPrecondition: http.StatusBadRequest,
+	codes.Aborted:            http.StatusConflict,
+	codes.OutOfRange:         http.StatusBadRequest,
+	codes.Unimplemented:      http.StatusNotImplemented,
+	codes.Internal:           http.StatusInternalServerError,
+	codes.Unavailable:        http.StatusServiceUnavailable,
+	codes.DataLoss:           http.StatusInternalServerError,
+	codes.Unauthenticated:    http.StatusUnauthorized,
+}
+
+var connectCodeNames = map[codes.Code]string{
+	codes.Canceled:           "canceled",
+	codes.Unknown:            "unknown",
+	codes.InvalidArgument:    "invalid_argument",
+	codes.DeadlineExceeded:   "deadline_exceeded",
+	codes.NotFound:           "not_found",
+	codes.AlreadyExists:      "already_exists",
+	codes.PermissionDenied:   "permission_denied",
+	codes.ResourceExhausted:  "resource_exhausted",
+	codes.FailedPrecondition: "failed_precondition",
+	codes.Aborted:            "aborted",
+	codes.OutOfRange:         "out_of_range",
+	codes.Unimplemented:      "unimplemented",
+	codes.Internal:           "internal",
+	codes.Unavailable:        "unavailable",
+	codes.DataLoss:           "data_loss",
+	codes.Unauthenticated:    "unauthenticated",
+}
+
+type connectError struct {
+	Code    string          `json:"code"`
+	Message string          `json:"message,omitempty"`
+	Details []connectDetail `json:"details,omitempty"`
+}
+
+type connectDetail struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type connectEndStream struct {
+	Error    *connectError       `json:"error,omitempty"`
+	Metadata map[string][]string `json:"metadata,omitempty"`
+}
+
+// newConnectError returns the Connect representation of err. It returns nil if err is nil.
+func newConnectError(err error) *connectError {
+	if err == nil {
+		return nil
+	}
+
+	st := status.Convert(err)
+
+	name, ok := connectCodeNames[st.Code()]
+	if !ok {
+		name = connectCodeNames[codes.Unknown]
+	}
+
+	e := connectError{
+		Code:    name,
+		Message: st.Message(),
+	}
+
+	for _, detail := range st.Proto().GetDetails() {
+		typeURL := detail.GetTypeUrl()
+
+		e.Details = append(e.Details, connectDetail{
+			Type:  typeURL[strings.LastIndex(typeURL, "/")+1:],
+			Value: base64.RawStdEncoding.EncodeToString(detail.GetValue()),
+		})
+	}
+
+	return &e
+}
+
+func connectHTTPStatus(err error) int {
+	if code, ok := connectCodeToHTTP[status.Code(err)]; ok {
+		return code
+	}
+
+	return http.StatusInternalServerError
+}
+
+// writeConnectError writes a unary error response.
+func writeConnectError(w http.ResponseWriter, err error) {
+	w.Header().Del("Content-Encoding")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(connectHTTPStatus(err))
+
+	_ = json.NewEncoder(w).Encode(newConnectError(err))
+}
+
+// writeConnectEndStream writes the end-of-stream message of a streaming response.
+func writeConnectEndStream(w io.Writer, trailer http.Header, err error) error {
+	end := connectEndStream{
+		Error: newConnectError(err),
+	}
+
+	if len(trailer) > 0 {
+		end.Metadata = make(map[string][]string, len(trailer))
+		for k, v := range trailer {
+			end.Metadata[strings.ToLower(k)] = v
+		}
+	}
+
+	data, err := json.Marshal(end)
+	if err != nil {
+		return err
+	}
+
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	var header [frameHeaderLen]byte
+
+	header[0] = flagEndStream
+	binary.BigEndian.PutUint32(header[1:], uint32(len(data)))
+
+	_, _ = buf.Write(header[:])
+	_, _ = buf.Write(data)
+
+	_, err = w.Write(buf.Bytes())
+
+	return err
+}
+
+// connectGetRequest converts a Connect GET request into the equivalent unary POST, so it
+// can be served the same way. The message, its codec and compression are query parameters.
+func connectGetRequest(r *http.Request) error {
+	query := r.URL.Query()
+
+	encoding := query.Get("encoding")
+	if encoding == "" {
+		return errors.New("missing encoding query parameter")
+	}
+
+	message := []byte(query.Get("message"))
+
+	if query.Get("base64") == "1" {
+		// padding is optional
+		decoded, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(string(message), "="))
+		if err != nil {
+			return fmt.Errorf("invalid message query parameter: %w", err)
+		}
+
+		message = decoded
+	}
+
+	r.Header.Set("Content-Type", connectUnaryContentTypePrefix+encoding)
+
+	if compression := query.Get("compression"); compression != "" {
+		r.Header.Set("Content-Encoding", compression)
+	} else {
+		r.Header.Del("Content-Encoding")
+	}
+
+	r.Body = ioutil.NopCloser(bytes.NewReader(message))
+
+	return nil
+}
+
+// decodeConnectTimeout parses Connect-Timeout-Ms, which is at most 10 digits.
+func decodeConnectTimeout(v string) (time.Duration, error) {
+	if len(v) > 10 {
+		return 0, status.Errorf(codes.InvalidArgument, "timeout %q is too long", v)
+	}
+
+	ms, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || ms < 0 {
+		return 0, status.Errorf(codes.InvalidArgument, "invalid timeout %q", v)
+	}
+
+	return time.Duration(ms) * time.Millisecond, nil
+}
+
+// recvConnectUnary reads the body of a unary request, which is a single message.
+func (s *serverStream) recvConnectUnary(m interface{}) error {
+	return s.recvUnary(func(body []byte) error {
+		return unmarshal(s.reader.codec, body, m)
+	})
+}
+
+// recvUnary reads and decompresses the request body, once. It returns io.EOF if the body
+// has already been read.
+func (s *serverStream) recvUnary(decode func(body []byte) error) error {
+	if s.unaryReceived {
+		return io.EOF
+	}
+
+	s.unaryReceived = true
+
+	if sc, ok := s.reader.compressor.(StreamCompressor); ok {
+		return s.recvUnaryCompressed(sc, decode)
+	}
+
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	n, err := buf.ReadFrom(io.LimitReader(s.reader.reader, readLimit(s.reader.maxSize)))
+	if err != nil {
+		return err
+	}
+
+	if n > int64(s.reader.maxSize) {
+		return status.Errorf(codes.ResourceExhausted, "received message larger than max (%d)", s.reader.maxSize)
+	}
+
+	body := buf.Bytes()
+
+	if s.reader.compressor != nil {
+		body, err = decompress(s.reader.compressor, body, s.reader.maxSize)
+		if err != nil {
+			return err
+		}
+	}
+
+	return decode(body)
+}
+
+// recvUnaryCompressed decompresses the request body as it is read, so a body that
+// decompresses to more than the max is rejected without decompressing all of it.
+func (s *serverStream) recvUnaryCompressed(sc StreamCompressor, decode func(body []byte) error) error {
+	body := &io.LimitedReader{R: s.reader.reader, N: readLimit(s.reader.maxSize)}
+
+	r, err := sc.NewReader(body)
+	if err != nil {
+		return err
+	}
+
+	if c, ok := r.(io.Closer); ok {
+		defer c.Close()
+	}
+
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	n, err := buf.ReadFrom(io.LimitReader(r, readLimit(s.reader.maxSize)))
+	if n > int64(s.reader.maxSize) {
+		return status.Errorf(codes.ResourceExhausted, "received message after decompression larger than max (%d)", s.reader.maxSize)
+	}
+
+	if body.N == 0 {
+		return status.Errorf(codes.ResourceExhausted, "received message larger than max (%d)", s.reader.maxSize)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return decode(buf.Bytes())
+}
+
+// sendConnectUnary holds the response of a unary request until the status is known,
+// as the status determines the HTTP status code.
+func (s *serverStream) sendConnectUnary(m interface{}) error {
+	data, err := s.frameWriter.codec.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	return s.setUnaryResponse(data)
+}
+
+// setUnaryResponse compresses and holds the response body.
+func (s *serverStream) setUnaryResponse(data []byte) error {
+	var err error
+
+	if c := s.frameWriter.compressor; c != nil {
+		if len(data) < s.frameWriter.minCompressSize {
+			s.writer.Header().Del("Content-Encoding")
+		} else {
+			data, err = c.Compress(data)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(data) > s.frameWriter.maxSize {
+		return status.Errorf(codes.ResourceExhausted, "trying to send message larger than max (%d vs. %d)", len(data), s.frameWriter.maxSize)
+	}
+
+	s.unaryResponse = data
+
+	return nil
+}
+
+// writeConnectUnary writes the response of a unary request. Trailers are sent as
+// headers with a Trailer- prefix.
+func (s *serverStream) writeConnectUnary(err error) {
+	s.headerSent = true
+
+	header := s.writer.Header()
+	setMetadataHeader(header, s.header)
+
+	trailer := make(http.Header)
+	setMetadataHeader(trailer, s.trailer)
+
+	for k, vv := range trailer {
+		for _, v := range vv {
+			header.Add("Trailer-"+k, v)
+		}
+	}
+
+	if err != nil {
+		writeConnectError(s.writer, err)
+		return
+	}
+
+	header.Set("Content-Length", strconv.Itoa(len(s.unaryResponse)))
+	s.writer.WriteHeader(http.StatusOK)
+
+	_, _ = s.writer.Write(s.unaryResponse)
+}
@@ -0,0 +1,82 @@
+package simplegrpc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/bakins/simplegrpc/codes"
+	"github.com/bakins/simplegrpc/status"
+)
+
+func TestRecvUnaryCompressed(t *testing.T) {
+	tests := []struct {
+		name       string
+		size       int
+		compressor Compressor
+		code       codes.Code
+	}{
+		{
+			name:       "within max",
+			size:       1024,
+			compressor: GzipCompressor,
+		},
+		{
+			name:       "larger than max",
+			size:       8 << 20,
+			compressor: GzipCompressor,
+			code:       codes.ResourceExhausted,
+		},
+		{
+			name:       "larger than max without stream",
+			size:       8 << 20,
+			compressor: bytesCompressor{GzipCompressor},
+			code:       codes.ResourceExhausted,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			data, err := ProtoCodec.Marshal(&wrapperspb.BytesValue{Value: make([]byte, test.size)})
+			require.NoError(t, err)
+
+			var compressed bytes.Buffer
+			w := gzip.NewWriter(&compressed)
+			_, err = w.Write(data)
+			require.NoError(t, err)
+			require.NoError(t, w.Close())
+
+			body := bytes.NewReader(compressed.Bytes())
+
+			s := serverStream{
+				reader: frameReader{
+					reader:     body,
+					codec:      ProtoCodec,
+					compressor: test.compressor,
+					maxSize:    64 << 10,
+				},
+			}
+
+			var msg wrapperspb.BytesValue
+			err = s.recvConnectUnary(&msg)
+
+			if test.code == codes.OK {
+				require.NoError(t, err)
+				require.Len(t, msg.Value, test.size)
+				return
+			}
+
+			require.Equal(t, test.code, status.Code(err))
+
+			if _, ok := test.compressor.(StreamCompressor); ok {
+				// the body is rejected once the max is exceeded, rather than after
+				// it has been decompressed.
+				require.NotZero(t, body.Len())
+			}
+		})
+	}
+}
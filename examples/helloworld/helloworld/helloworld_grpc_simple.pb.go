@@ -15,7 +15,7 @@ const _ = simplegrpc.SupportPackageIsVersion1
 // GreeterSimpleClient is the client API for Greeter service.
 type GreeterSimpleClient interface {
 	// Sends a greeting
-	SayHello(ctx context.Context, in *HelloRequest) (*HelloReply, error)
+	SayHello(ctx context.Context, in *HelloRequest, opts ...simplegrpc.CallOption) (*HelloReply, error)
 }
 
 type greeterSimpleClient struct {
@@ -26,16 +26,9 @@ func NewGreeterSimpleClient(cc simplegrpc.ClientConn) GreeterSimpleClient {
 	return &greeterSimpleClient{cc: cc}
 }
 
-func (c *greeterSimpleClient) SayHello(ctx context.Context, in *HelloRequest) (*HelloReply, error) {
-	stream, err := c.cc.NewStream(ctx, &_Greeter_simple_serviceDesc.Streams[0], "/helloworld.Greeter/SayHello")
-	if err != nil {
-		return nil, err
-	}
-	if err := stream.SendMsg(in); err != nil {
-		return nil, err
-	}
+func (c *greeterSimpleClient) SayHello(ctx context.Context, in *HelloRequest, opts ...simplegrpc.CallOption) (*HelloReply, error) {
 	var out HelloReply
-	if err := stream.RecvMsg(&out); err != nil {
+	if err := c.cc.Invoke(ctx, "/helloworld.Greeter/SayHello", in, &out, opts...); err != nil {
 		return nil, err
 	}
 	return &out, nil
@@ -51,20 +44,26 @@ func RegisterGreeterSimpleServer(s simplegrpc.ServiceRegistrar, srv GreeterSimpl
 	s.RegisterService(&_Greeter_simple_serviceDesc, srv)
 }
 
-func _Greeter_SayHello_Simple_Handler(srv interface{}, stream simplegrpc.ServerStream) error {
+func _Greeter_SayHello_Simple_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor simplegrpc.UnaryServerInterceptor) (interface{}, error) {
 	impl, ok := srv.(GreeterSimpleServer)
 	if !ok {
-		return errors.New("invalid server type - expected GreeterSimpleServer")
+		return nil, errors.New("invalid server type - expected GreeterSimpleServer")
+	}
+	in := new(HelloRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return impl.SayHello(ctx, in)
 	}
-	var in HelloRequest
-	if err := stream.RecvMsg(&in); err != nil {
-		return err
+	info := &simplegrpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/helloworld.Greeter/SayHello",
 	}
-	out, err := impl.SayHello(stream.Context(), &in)
-	if err != nil {
-		return err
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return impl.SayHello(ctx, req.(*HelloRequest))
 	}
-	return stream.SendMsg(out)
+	return interceptor(ctx, in, info, handler)
 }
 
 var _Greeter_simple_serviceDesc = simplegrpc.ServiceDesc{
@@ -73,7 +72,7 @@ var _Greeter_simple_serviceDesc = simplegrpc.ServiceDesc{
 	Streams: []simplegrpc.StreamDesc{
 		{
 			StreamName:    "SayHello",
-			Handler:       _Greeter_SayHello_Simple_Handler,
+			MethodHandler: _Greeter_SayHello_Simple_Handler,
 			ServerStreams: false,
 			ClientStreams: false,
 		},
@@ -1,10 +1,20 @@
 package helloworld
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"io/ioutil"
+	"math"
 	"net"
+	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -12,27 +22,47 @@ import (
 	"github.com/stretchr/testify/require"
 	"golang.org/x/net/http2"
 	"golang.org/x/net/http2/h2c"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
+	grpccodes "google.golang.org/grpc/codes"
 	_ "google.golang.org/grpc/encoding/gzip"
+	grpcmetadata "google.golang.org/grpc/metadata"
+	grpcstatus "google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 
 	"github.com/bakins/simplegrpc"
 	"github.com/bakins/simplegrpc/codes"
+	"github.com/bakins/simplegrpc/metadata"
 	"github.com/bakins/simplegrpc/status"
 )
 
-func TestSayHello(t *testing.T) {
-	h := simplegrpc.NewHandler()
+// newServer serves srv with a Handler created with options. The Handler also
+// accepts gzip and JSON. The server is closed when the test ends.
+func newServer(t *testing.T, srv GreeterSimpleServer, options ...simplegrpc.HandlerOption) *httptest.Server {
+	h := simplegrpc.NewHandler(options...)
+	h.RegisterCodec(simplegrpc.JSONCodec)
 	h.RegisterCompressor(simplegrpc.GzipCompressor)
 
-	RegisterGreeterSimpleServer(h, &server{})
+	RegisterGreeterSimpleServer(h, srv)
 
 	svr := httptest.NewServer(h2c.NewHandler(h, &http2.Server{}))
-	defer svr.Close()
+	t.Cleanup(svr.Close)
 
-	conn, err := simplegrpc.NewClientConn(svr.URL)
+	return svr
+}
+
+// setup serves srv as newServer does, and returns a client of it created with clientOptions.
+func setup(t *testing.T, srv GreeterSimpleServer, handlerOptions []simplegrpc.HandlerOption, clientOptions ...simplegrpc.Option) GreeterSimpleClient {
+	svr := newServer(t, srv, handlerOptions...)
+
+	conn, err := simplegrpc.NewClientConn(svr.URL, clientOptions...)
 	require.NoError(t, err)
 
-	client := NewGreeterSimpleClient(conn)
+	return NewGreeterSimpleClient(conn)
+}
+
+func TestSayHello(t *testing.T) {
+	client := setup(t, &server{}, nil)
 
 	req := HelloRequest{
 		Name: "world",
@@ -123,11 +153,7 @@ func TestSayHelloClient(t *testing.T) {
 }
 
 func TestSayHelloServer(t *testing.T) {
-	h := simplegrpc.NewHandler()
-	RegisterGreeterSimpleServer(h, &server{})
-
-	svr := httptest.NewServer(h2c.NewHandler(h, &http2.Server{}))
-	defer svr.Close()
+	svr := newServer(t, &server{})
 
 	u, err := url.Parse(svr.URL)
 	require.NoError(t, err)
@@ -149,3 +175,1052 @@ func TestSayHelloServer(t *testing.T) {
 
 	require.Equal(t, "Hello world", resp.Message)
 }
+
+type deadlineServer struct {
+	UnimplementedGreeterServer
+}
+
+// SayHello replies with the time remaining before the deadline, or blocks until the deadline
+// if the name is "wait"
+func (s *deadlineServer) SayHello(ctx context.Context, in *HelloRequest) (*HelloReply, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return nil, status.Error(codes.FailedPrecondition, "no deadline set")
+	}
+
+	if in.GetName() == "wait" {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	return &HelloReply{Message: time.Until(deadline).String()}, nil
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestSayHelloDeadline(t *testing.T) {
+	client := setup(t, &deadlineServer{}, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	resp, err := client.SayHello(ctx, &HelloRequest{Name: "world"})
+	require.NoError(t, err)
+
+	remaining, err := time.ParseDuration(resp.Message)
+	require.NoError(t, err)
+	require.True(t, remaining > 0 && remaining <= time.Second*5, remaining.String())
+}
+
+func TestSayHelloDeadlineExceeded(t *testing.T) {
+	// shorten the timeout sent to the server so it expires before the client deadline
+	wrapper := func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			r.Header.Set("Grpc-Timeout", "10m")
+			return next.RoundTrip(r)
+		})
+	}
+
+	client := setup(t, &deadlineServer{}, nil, simplegrpc.WithTransportWrapper(wrapper))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	_, err := client.SayHello(ctx, &HelloRequest{Name: "wait"})
+	require.Error(t, err)
+
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	require.Equal(t, codes.DeadlineExceeded, st.Code())
+}
+
+type metadataServer struct {
+	UnimplementedGreeterServer
+}
+
+// SayHello replies with the incoming metadata values
+func (s *metadataServer) SayHello(ctx context.Context, in *HelloRequest) (*HelloReply, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.FailedPrecondition, "no metadata")
+	}
+
+	if len(md.Get("grpc-timeout")) != 0 {
+		return nil, status.Error(codes.FailedPrecondition, "reserved header found in metadata")
+	}
+
+	values := append(md.Get("x-name"), md.Get("x-data-bin")...)
+
+	return &HelloReply{Message: strings.Join(values, ",")}, nil
+}
+
+func TestSayHelloMetadata(t *testing.T) {
+	client := setup(t, &metadataServer{}, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	ctx = metadata.NewOutgoingContext(ctx, metadata.Pairs("X-Name", "world"))
+	ctx = metadata.AppendToOutgoingContext(ctx, "x-data-bin", "\x00\x01binary")
+
+	resp, err := client.SayHello(ctx, &HelloRequest{Name: "world"})
+	require.NoError(t, err)
+
+	require.Equal(t, "world,\x00\x01binary", resp.Message)
+}
+
+type headerServer struct {
+	UnimplementedGreeterServer
+}
+
+// SayHello sets response header and trailer metadata
+func (s *headerServer) SayHello(ctx context.Context, in *HelloRequest) (*HelloReply, error) {
+	if err := simplegrpc.SetHeader(ctx, metadata.Pairs("x-header", "header")); err != nil {
+		return nil, err
+	}
+
+	if err := simplegrpc.SetTrailer(ctx, metadata.Pairs("x-trailer", "trailer", "x-trailer-bin", "\x00\x01")); err != nil {
+		return nil, err
+	}
+
+	if in.GetName() == "error" {
+		return nil, status.Error(codes.InvalidArgument, "invalid name")
+	}
+
+	return &HelloReply{Message: "Hello " + in.GetName()}, nil
+}
+
+func TestSayHelloServerMetadata(t *testing.T) {
+	svr := newServer(t, &headerServer{})
+
+	u, err := url.Parse(svr.URL)
+	require.NoError(t, err)
+
+	conn, err := grpc.Dial(u.Host, grpc.WithInsecure())
+	require.NoError(t, err)
+
+	defer conn.Close()
+
+	client := NewGreeterClient(conn)
+
+	for _, name := range []string{"world", "error"} {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+
+		var header, trailer grpcmetadata.MD
+
+		_, err = client.SayHello(ctx, &HelloRequest{Name: name}, grpc.Header(&header), grpc.Trailer(&trailer))
+		cancel()
+
+		if name == "error" {
+			require.Error(t, err)
+		} else {
+			require.NoError(t, err)
+		}
+
+		require.Equal(t, []string{"header"}, header.Get("x-header"), name)
+		require.Equal(t, []string{"trailer"}, trailer.Get("x-trailer"), name)
+		require.Equal(t, []string{"\x00\x01"}, trailer.Get("x-trailer-bin"), name)
+	}
+}
+
+type sendHeaderServer struct {
+	UnimplementedGreeterServer
+}
+
+// SayHello sends the header, and fails if the header can be changed afterwards.
+func (s *sendHeaderServer) SayHello(ctx context.Context, in *HelloRequest) (*HelloReply, error) {
+	if err := simplegrpc.SendHeader(ctx, metadata.Pairs("x-header", "header")); err != nil {
+		return nil, err
+	}
+
+	if err := simplegrpc.SendHeader(ctx, metadata.Pairs("x-header", "again")); err == nil {
+		return nil, status.Error(codes.Internal, "header sent twice")
+	}
+
+	if err := simplegrpc.SetHeader(ctx, metadata.Pairs("x-header", "set")); err == nil {
+		return nil, status.Error(codes.Internal, "header set after it was sent")
+	}
+
+	return &HelloReply{Message: "Hello " + in.GetName()}, nil
+}
+
+func TestSayHelloSendHeader(t *testing.T) {
+	svr := newServer(t, &sendHeaderServer{})
+
+	conn, err := simplegrpc.NewClientConn(svr.URL)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	var header metadata.MD
+
+	_, err = NewGreeterSimpleClient(conn).SayHello(ctx, &HelloRequest{Name: "world"}, simplegrpc.Header(&header))
+	require.NoError(t, err)
+	require.Equal(t, []string{"header"}, header.Get("x-header"))
+
+	// the headers of Connect unary requests are sent with the response
+	body, err := simplegrpc.ProtoCodec.Marshal(&HelloRequest{Name: "world"})
+	require.NoError(t, err)
+
+	req, err := http.NewRequest(http.MethodPost, svr.URL+"/helloworld.Greeter/SayHello", bytes.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/proto")
+
+	resp, err := svr.Client().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, []string{"header"}, resp.Header.Values("x-header"))
+}
+
+func TestSayHelloClientMetadata(t *testing.T) {
+	client := setup(t, &headerServer{}, nil)
+
+	for _, name := range []string{"world", "error"} {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+
+		var header, trailer metadata.MD
+
+		_, err := client.SayHello(ctx, &HelloRequest{Name: name}, simplegrpc.Header(&header), simplegrpc.Trailer(&trailer))
+		cancel()
+
+		if name == "error" {
+			require.Error(t, err)
+		} else {
+			require.NoError(t, err)
+		}
+
+		require.Equal(t, []string{"header"}, header.Get("x-header"), name)
+		require.Equal(t, []string{"trailer"}, trailer.Get("x-trailer"), name)
+		require.Equal(t, []string{"\x00\x01"}, trailer.Get("x-trailer-bin"), name)
+		require.Empty(t, trailer.Get("grpc-status"), name)
+	}
+}
+
+func TestSayHelloInterceptors(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		calls []string
+	)
+
+	record := func(call string) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, call)
+	}
+
+	stream := func(name string) simplegrpc.StreamServerInterceptor {
+		return func(srv interface{}, ss simplegrpc.ServerStream, info *simplegrpc.StreamServerInfo, handler simplegrpc.StreamHandler) error {
+			record(name + " " + info.FullMethod)
+			return handler(srv, ss)
+		}
+	}
+
+	unary := func(name string) simplegrpc.UnaryServerInterceptor {
+		return func(ctx context.Context, req interface{}, info *simplegrpc.UnaryServerInfo, handler simplegrpc.UnaryHandler) (interface{}, error) {
+			record(name + " " + req.(*HelloRequest).GetName())
+
+			resp, err := handler(ctx, req)
+			if err != nil {
+				return nil, err
+			}
+
+			reply := resp.(*HelloReply)
+			reply.Message += " " + name
+
+			return reply, nil
+		}
+	}
+
+	client := setup(t, &server{}, []simplegrpc.HandlerOption{
+		simplegrpc.WithChainUnaryInterceptor(unary("chain1"), unary("chain2")),
+		simplegrpc.WithUnaryInterceptor(unary("unary")),
+		simplegrpc.WithChainStreamInterceptor(stream("chain1")),
+		simplegrpc.WithStreamInterceptor(stream("stream")),
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	resp, err := client.SayHello(ctx, &HelloRequest{Name: "world"})
+	require.NoError(t, err)
+
+	require.Equal(t, "Hello world chain2 chain1 unary", resp.Message)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	expected := []string{
+		"stream /helloworld.Greeter/SayHello",
+		"chain1 /helloworld.Greeter/SayHello",
+		"unary world",
+		"chain1 world",
+		"chain2 world",
+	}
+	require.Equal(t, expected, calls)
+}
+
+func TestSayHelloClientInterceptors(t *testing.T) {
+	// each interceptor adds its name to the metadata, so the server sees the call order
+	stream := func(name string) simplegrpc.StreamClientInterceptor {
+		return func(ctx context.Context, desc *simplegrpc.StreamDesc, cc simplegrpc.ClientConn, method string, streamer simplegrpc.Streamer) (simplegrpc.ClientStream, error) {
+			ctx = metadata.AppendToOutgoingContext(ctx, "x-name", name)
+			return streamer(ctx, desc, cc, method)
+		}
+	}
+
+	unary := func(name string) simplegrpc.UnaryClientInterceptor {
+		return func(ctx context.Context, method string, req, reply interface{}, cc simplegrpc.ClientConn, invoker simplegrpc.UnaryInvoker, opts ...simplegrpc.CallOption) error {
+			ctx = metadata.AppendToOutgoingContext(ctx, "x-name", name+" "+req.(*HelloRequest).GetName())
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+	}
+
+	client := setup(t, &metadataServer{}, nil,
+		simplegrpc.WithChainUnaryClientInterceptor(unary("chain1"), unary("chain2")),
+		simplegrpc.WithUnaryClientInterceptor(unary("unary")),
+		simplegrpc.WithChainStreamClientInterceptor(stream("chain1")),
+		simplegrpc.WithStreamClientInterceptor(stream("stream")),
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	resp, err := client.SayHello(ctx, &HelloRequest{Name: "world"})
+	require.NoError(t, err)
+
+	require.Equal(t, "unary world,chain1 world,chain2 world,stream,chain1", resp.Message)
+}
+
+type detailsServer struct {
+	UnimplementedGreeterServer
+}
+
+var badRequest = &errdetails.BadRequest{
+	FieldViolations: []*errdetails.BadRequest_FieldViolation{
+		{
+			Field:       "name",
+			Description: "name is required",
+		},
+	},
+}
+
+// SayHello always fails with error details
+func (s *detailsServer) SayHello(ctx context.Context, in *HelloRequest) (*HelloReply, error) {
+	st, err := status.New(codes.InvalidArgument, "invalid name").WithDetails(badRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, st
+}
+
+type grpcDetailsServer struct {
+	UnimplementedGreeterServer
+}
+
+// SayHello always fails with error details
+func (s *grpcDetailsServer) SayHello(ctx context.Context, in *HelloRequest) (*HelloReply, error) {
+	st, err := grpcstatus.New(grpccodes.InvalidArgument, "invalid name").WithDetails(badRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, st.Err()
+}
+
+func TestSayHelloErrorDetails(t *testing.T) {
+	client := setup(t, &detailsServer{}, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	_, err := client.SayHello(ctx, &HelloRequest{Name: "world"})
+	require.Error(t, err)
+
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	require.Equal(t, codes.InvalidArgument, st.Code())
+	require.Equal(t, "invalid name", st.Message())
+
+	details := st.Details()
+	require.Len(t, details, 1)
+	require.True(t, proto.Equal(badRequest, details[0].(proto.Message)))
+}
+
+func TestSayHelloServerErrorDetails(t *testing.T) {
+	svr := newServer(t, &detailsServer{})
+
+	u, err := url.Parse(svr.URL)
+	require.NoError(t, err)
+
+	conn, err := grpc.Dial(u.Host, grpc.WithInsecure())
+	require.NoError(t, err)
+
+	defer conn.Close()
+
+	client := NewGreeterClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	_, err = client.SayHello(ctx, &HelloRequest{Name: "world"})
+	require.Error(t, err)
+
+	st := grpcstatus.Convert(err)
+	require.Equal(t, grpccodes.InvalidArgument, st.Code())
+
+	details := st.Details()
+	require.Len(t, details, 1)
+	require.True(t, proto.Equal(badRequest, details[0].(proto.Message)))
+}
+
+func TestSayHelloClientErrorDetails(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	g := grpc.NewServer()
+
+	RegisterGreeterServer(g, &grpcDetailsServer{})
+
+	defer func() {
+		g.Stop()
+		_ = lis.Close()
+	}()
+
+	go func() {
+		err := g.Serve(lis)
+		assert.NoError(t, err)
+	}()
+
+	conn, err := simplegrpc.NewClientConn("http://" + lis.Addr().String())
+	require.NoError(t, err)
+
+	client := NewGreeterSimpleClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	_, err = client.SayHello(ctx, &HelloRequest{Name: "world"})
+	require.Error(t, err)
+
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	require.Equal(t, codes.InvalidArgument, st.Code())
+
+	details := st.Details()
+	require.Len(t, details, 1)
+	require.True(t, proto.Equal(badRequest, details[0].(proto.Message)))
+}
+
+func TestSayHelloGRPCErrorDetails(t *testing.T) {
+	// the handler understands errors from google.golang.org/grpc/status
+	client := setup(t, &grpcDetailsServer{}, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	_, err := client.SayHello(ctx, &HelloRequest{Name: "world"})
+	require.Error(t, err)
+
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	require.Equal(t, codes.InvalidArgument, st.Code())
+	require.Equal(t, "invalid name", st.Message())
+
+	details := st.Details()
+	require.Len(t, details, 1)
+	require.True(t, proto.Equal(badRequest, details[0].(proto.Message)))
+}
+
+func TestSayHelloClientErrors(t *testing.T) {
+	h := simplegrpc.NewHandler()
+	RegisterGreeterSimpleServer(h, &deadlineServer{})
+
+	httpStatus := func(code int) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(code)
+		})
+	}
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// nothing is listening on a closed listener
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	require.NoError(t, lis.Close())
+
+	tests := []struct {
+		name     string
+		handler  http.Handler
+		endpoint string
+		ctx      context.Context
+		timeout  time.Duration
+		request  string
+		code     codes.Code
+	}{
+		{
+			name:    "not found",
+			handler: httpStatus(http.StatusNotFound),
+			code:    codes.Unimplemented,
+		},
+		{
+			name:    "service unavailable",
+			handler: httpStatus(http.StatusServiceUnavailable),
+			code:    codes.Unavailable,
+		},
+		{
+			name:    "unauthorized",
+			handler: httpStatus(http.StatusUnauthorized),
+			code:    codes.Unauthenticated,
+		},
+		{
+			name:    "teapot",
+			handler: httpStatus(http.StatusTeapot),
+			code:    codes.Unknown,
+		},
+		{
+			name:    "canceled",
+			handler: h,
+			ctx:     canceled,
+			code:    codes.Canceled,
+		},
+		{
+			name:    "deadline exceeded",
+			handler: h,
+			timeout: time.Millisecond * 50,
+			request: "wait",
+			code:    codes.DeadlineExceeded,
+		},
+		{
+			name:     "connection refused",
+			endpoint: "http://" + lis.Addr().String(),
+			code:     codes.Unavailable,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			endpoint := test.endpoint
+			if test.handler != nil {
+				svr := httptest.NewServer(h2c.NewHandler(test.handler, &http2.Server{}))
+				defer svr.Close()
+
+				endpoint = svr.URL
+			}
+
+			conn, err := simplegrpc.NewClientConn(endpoint)
+			require.NoError(t, err)
+
+			client := NewGreeterSimpleClient(conn)
+
+			ctx := test.ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+
+			timeout := test.timeout
+			if timeout == 0 {
+				timeout = time.Second * 5
+			}
+
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			_, err = client.SayHello(ctx, &HelloRequest{Name: test.request})
+			require.Error(t, err)
+			require.Equal(t, test.code, status.Code(err), err.Error())
+		})
+	}
+}
+
+func TestSayHelloMessageSize(t *testing.T) {
+	name := strings.Repeat("a", 1024)
+
+	tests := []struct {
+		name          string
+		serverOptions []simplegrpc.HandlerOption
+		clientOptions []simplegrpc.Option
+		code          codes.Code
+	}{
+		{
+			name: "default",
+			code: codes.OK,
+		},
+		{
+			name:          "server receive",
+			serverOptions: []simplegrpc.HandlerOption{simplegrpc.WithMaxRecvMsgSize(512)},
+			code:          codes.ResourceExhausted,
+		},
+		{
+			name:          "server receive compressed",
+			serverOptions: []simplegrpc.HandlerOption{simplegrpc.WithMaxRecvMsgSize(512)},
+			clientOptions: []simplegrpc.Option{simplegrpc.WithCompressor(simplegrpc.GzipCompressor)},
+			code:          codes.ResourceExhausted,
+		},
+		{
+			name:          "server receive compressed without limit",
+			serverOptions: []simplegrpc.HandlerOption{simplegrpc.WithMaxRecvMsgSize(math.MaxInt64)},
+			clientOptions: []simplegrpc.Option{simplegrpc.WithCompressor(simplegrpc.GzipCompressor)},
+			code:          codes.OK,
+		},
+		{
+			name:          "client receive compressed without limit",
+			serverOptions: []simplegrpc.HandlerOption{simplegrpc.WithCompressorPreference("gzip")},
+			clientOptions: []simplegrpc.Option{simplegrpc.WithMaxRecvMsgSize(math.MaxInt64), simplegrpc.WithDecompressor(simplegrpc.GzipCompressor)},
+			code:          codes.OK,
+		},
+		{
+			name:          "server send",
+			serverOptions: []simplegrpc.HandlerOption{simplegrpc.WithMaxSendMsgSize(512)},
+			code:          codes.ResourceExhausted,
+		},
+		{
+			name:          "client receive",
+			clientOptions: []simplegrpc.Option{simplegrpc.WithMaxRecvMsgSize(512)},
+			code:          codes.ResourceExhausted,
+		},
+		{
+			name:          "client send",
+			clientOptions: []simplegrpc.Option{simplegrpc.WithMaxSendMsgSize(512)},
+			code:          codes.ResourceExhausted,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			client := setup(t, &server{}, test.serverOptions, test.clientOptions...)
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+			defer cancel()
+
+			resp, err := client.SayHello(ctx, &HelloRequest{Name: name})
+			require.Equal(t, test.code, status.Code(err))
+
+			if test.code == codes.OK {
+				require.Equal(t, "Hello "+name, resp.GetMessage())
+			}
+		})
+	}
+}
+
+func TestSayHelloCompression(t *testing.T) {
+	tests := []struct {
+		name          string
+		serverOptions []simplegrpc.HandlerOption
+		clientOptions []simplegrpc.Option
+		accept        string
+		encoding      string
+		code          codes.Code
+	}{
+		{
+			name:     "identity",
+			encoding: "",
+		},
+		{
+			name:          "request compressor",
+			clientOptions: []simplegrpc.Option{simplegrpc.WithCompressor(simplegrpc.GzipCompressor)},
+			encoding:      "gzip",
+		},
+		{
+			name:          "preference",
+			serverOptions: []simplegrpc.HandlerOption{simplegrpc.WithCompressorPreference("gzip")},
+			clientOptions: []simplegrpc.Option{simplegrpc.WithDecompressor(simplegrpc.GzipCompressor)},
+			encoding:      "gzip",
+		},
+		{
+			name:          "preference not accepted",
+			serverOptions: []simplegrpc.HandlerOption{simplegrpc.WithCompressorPreference("gzip")},
+			encoding:      "",
+		},
+		{
+			name:          "preference not registered",
+			serverOptions: []simplegrpc.HandlerOption{simplegrpc.WithCompressorPreference("snappy")},
+			clientOptions: []simplegrpc.Option{simplegrpc.WithCompressor(simplegrpc.GzipCompressor)},
+			encoding:      "",
+		},
+		{
+			name:          "decompressor not installed",
+			serverOptions: []simplegrpc.HandlerOption{simplegrpc.WithCompressorPreference("gzip")},
+			accept:        "gzip",
+			encoding:      "gzip",
+			code:          codes.Internal,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			var response http.Header
+
+			wrapper := func(next http.RoundTripper) http.RoundTripper {
+				return roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+					if test.accept != "" {
+						r.Header.Set("Grpc-Accept-Encoding", test.accept)
+					}
+
+					resp, err := next.RoundTrip(r)
+					if resp != nil {
+						response = resp.Header
+					}
+
+					return resp, err
+				})
+			}
+
+			options := append([]simplegrpc.Option{simplegrpc.WithTransportWrapper(wrapper)}, test.clientOptions...)
+
+			client := setup(t, &server{}, test.serverOptions, options...)
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+			defer cancel()
+
+			resp, err := client.SayHello(ctx, &HelloRequest{Name: "world"})
+			require.Equal(t, test.code, status.Code(err))
+
+			require.Equal(t, test.encoding, response.Get("Grpc-Encoding"))
+			require.Equal(t, "gzip", response.Get("Grpc-Accept-Encoding"))
+
+			if test.code == codes.OK {
+				require.Equal(t, "Hello world", resp.Message)
+			}
+		})
+	}
+}
+
+func TestSayHelloJSON(t *testing.T) {
+	var contentType string
+
+	wrapper := func(next http.RoundTripper) http.RoundTripper {
+		return roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			require.Equal(t, "application/grpc+json", r.Header.Get("Content-Type"))
+
+			resp, err := next.RoundTrip(r)
+			if resp != nil {
+				contentType = resp.Header.Get("Content-Type")
+			}
+
+			return resp, err
+		})
+	}
+
+	client := setup(t, &server{}, nil, simplegrpc.WithCodec(simplegrpc.JSONCodec), simplegrpc.WithTransportWrapper(wrapper))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	resp, err := client.SayHello(ctx, &HelloRequest{Name: "world"})
+	require.NoError(t, err)
+	require.Equal(t, "Hello world", resp.Message)
+	require.Equal(t, "application/grpc+json", contentType)
+}
+
+// grpcWebResponse holds the messages and trailers of a gRPC-Web response.
+type grpcWebResponse struct {
+	messages [][]byte
+	trailer  http.Header
+}
+
+func parseGRPCWebResponse(t *testing.T, body []byte) grpcWebResponse {
+	var resp grpcWebResponse
+
+	for len(body) > 0 {
+		require.True(t, len(body) >= 5)
+
+		flag := body[0]
+		length := binary.BigEndian.Uint32(body[1:5])
+		data := body[5 : 5+length]
+		body = body[5+length:]
+
+		if flag&0x80 == 0 {
+			resp.messages = append(resp.messages, data)
+			continue
+		}
+
+		resp.trailer = make(http.Header)
+
+		for _, line := range strings.Split(strings.TrimSpace(string(data)), "\r\n") {
+			parts := strings.SplitN(line, ": ", 2)
+			require.Len(t, parts, 2)
+			resp.trailer.Add(parts[0], parts[1])
+		}
+	}
+
+	return resp
+}
+
+func TestSayHelloGRPCWeb(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		text        bool
+		code        codes.Code
+	}{
+		{
+			name:        "binary",
+			contentType: "application/grpc-web",
+		},
+		{
+			name:        "binary proto",
+			contentType: "application/grpc-web+proto",
+		},
+		{
+			name:        "text",
+			contentType: "application/grpc-web-text",
+			text:        true,
+		},
+		{
+			name:        "error",
+			contentType: "application/grpc-web",
+			code:        codes.NotFound,
+		},
+		{
+			name:        "text error",
+			contentType: "application/grpc-web-text",
+			text:        true,
+			code:        codes.NotFound,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			svr := newServer(t, &server{code: test.code})
+
+			data, err := proto.Marshal(&HelloRequest{Name: "world"})
+			require.NoError(t, err)
+
+			body := make([]byte, 5, 5+len(data))
+			binary.BigEndian.PutUint32(body[1:], uint32(len(data)))
+			body = append(body, data...)
+
+			if test.text {
+				body = []byte(base64.StdEncoding.EncodeToString(body))
+			}
+
+			req, err := http.NewRequest(http.MethodPost, svr.URL+"/helloworld.Greeter/SayHello", bytes.NewReader(body))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", test.contentType)
+
+			resp, err := svr.Client().Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			require.Equal(t, 1, resp.ProtoMajor)
+			require.Equal(t, http.StatusOK, resp.StatusCode)
+
+			expectedContentType := "application/grpc-web+proto"
+			if test.text {
+				expectedContentType = "application/grpc-web-text+proto"
+			}
+			require.Equal(t, expectedContentType, resp.Header.Get("Content-Type"))
+
+			respBody, err := ioutil.ReadAll(resp.Body)
+			require.NoError(t, err)
+
+			if test.text {
+				// each frame is encoded and padded separately, so decode a quantum at a time
+				require.Zero(t, len(respBody)%4)
+
+				var decoded []byte
+				for i := 0; i < len(respBody); i += 4 {
+					chunk, err := base64.StdEncoding.DecodeString(string(respBody[i : i+4]))
+					require.NoError(t, err)
+
+					decoded = append(decoded, chunk...)
+				}
+
+				respBody = decoded
+			}
+
+			webResp := parseGRPCWebResponse(t, respBody)
+			require.Equal(t, strconv.Itoa(int(test.code)), webResp.trailer.Get("grpc-status"))
+
+			if test.code != codes.OK {
+				require.Empty(t, webResp.messages)
+				return
+			}
+
+			require.Len(t, webResp.messages, 1)
+
+			var reply HelloReply
+			require.NoError(t, proto.Unmarshal(webResp.messages[0], &reply))
+			require.Equal(t, "Hello world", reply.Message)
+		})
+	}
+}
+
+func TestSayHelloGRPCWebCORS(t *testing.T) {
+	svr := newServer(t, &server{}, simplegrpc.WithCORS(func(origin string) bool {
+		return origin == "https://example.com"
+	}))
+
+	preflight := func(origin string) *http.Response {
+		req, err := http.NewRequest(http.MethodOptions, svr.URL+"/helloworld.Greeter/SayHello", nil)
+		require.NoError(t, err)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		req.Header.Set("Access-Control-Request-Headers", "content-type,x-grpc-web")
+
+		resp, err := svr.Client().Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+
+		return resp
+	}
+
+	resp := preflight("https://example.com")
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+	require.Equal(t, "https://example.com", resp.Header.Get("Access-Control-Allow-Origin"))
+	require.Equal(t, http.MethodPost, resp.Header.Get("Access-Control-Allow-Methods"))
+	require.Equal(t, "content-type,x-grpc-web", resp.Header.Get("Access-Control-Allow-Headers"))
+
+	resp = preflight("https://attacker.example")
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+	require.Empty(t, resp.Header.Get("Access-Control-Allow-Origin"))
+
+	data, err := proto.Marshal(&HelloRequest{Name: "world"})
+	require.NoError(t, err)
+
+	body := make([]byte, 5, 5+len(data))
+	binary.BigEndian.PutUint32(body[1:], uint32(len(data)))
+	body = append(body, data...)
+
+	req, err := http.NewRequest(http.MethodPost, svr.URL+"/helloworld.Greeter/SayHello", bytes.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/grpc-web+proto")
+	req.Header.Set("Origin", "https://example.com")
+
+	resp, err = svr.Client().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, "https://example.com", resp.Header.Get("Access-Control-Allow-Origin"))
+	require.Contains(t, resp.Header.Get("Access-Control-Expose-Headers"), "grpc-status")
+}
+
+func TestSayHelloConnect(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		encoding    string
+		options     []simplegrpc.HandlerOption
+		code        codes.Code
+		status      int
+	}{
+		{
+			name:        "proto",
+			contentType: "application/proto",
+			status:      http.StatusOK,
+		},
+		{
+			name:        "json",
+			contentType: "application/json",
+			status:      http.StatusOK,
+		},
+		{
+			name:        "without limit",
+			contentType: "application/proto",
+			options:     []simplegrpc.HandlerOption{simplegrpc.WithMaxRecvMsgSize(math.MaxInt64)},
+			status:      http.StatusOK,
+		},
+		{
+			name:        "compressed without limit",
+			contentType: "application/proto",
+			encoding:    "gzip",
+			options:     []simplegrpc.HandlerOption{simplegrpc.WithMaxRecvMsgSize(math.MaxInt64)},
+			status:      http.StatusOK,
+		},
+		{
+			name:        "error",
+			contentType: "application/proto",
+			code:        codes.NotFound,
+			status:      http.StatusNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			svr := newServer(t, &server{code: test.code}, test.options...)
+
+			codec := simplegrpc.ProtoCodec
+			if test.contentType == "application/json" {
+				codec = simplegrpc.JSONCodec
+			}
+
+			body, err := codec.Marshal(&HelloRequest{Name: "world"})
+			require.NoError(t, err)
+
+			if test.encoding != "" {
+				body, err = simplegrpc.GzipCompressor.Compress(body)
+				require.NoError(t, err)
+			}
+
+			req, err := http.NewRequest(http.MethodPost, svr.URL+"/helloworld.Greeter/SayHello", bytes.NewReader(body))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", test.contentType)
+			if test.encoding != "" {
+				req.Header.Set("Content-Encoding", test.encoding)
+			}
+			req.Header.Set("Connect-Protocol-Version", "1")
+			req.Header.Set("Connect-Timeout-Ms", "5000")
+
+			resp, err := svr.Client().Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			require.Equal(t, test.status, resp.StatusCode)
+
+			respBody, err := ioutil.ReadAll(resp.Body)
+			require.NoError(t, err)
+
+			if test.code != codes.OK {
+				require.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+				var connectErr struct {
+					Code    string `json:"code"`
+					Message string `json:"message"`
+				}
+
+				require.NoError(t, json.Unmarshal(respBody, &connectErr))
+				require.Equal(t, "not_found", connectErr.Code)
+				require.Equal(t, codes.NotFound.String(), connectErr.Message)
+
+				return
+			}
+
+			require.Equal(t, test.contentType, resp.Header.Get("Content-Type"))
+
+			var reply HelloReply
+			require.NoError(t, codec.Unmarshal(respBody, &reply))
+			require.Equal(t, "Hello world", reply.Message)
+		})
+	}
+}
+
+func TestSayHelloConnectGet(t *testing.T) {
+	svr := newServer(t, &server{})
+
+	query := url.Values{
+		"encoding": {"json"},
+		"message":  {`{"name":"world"}`},
+	}
+
+	// SayHello does not declare that it has no side effects
+	resp, err := svr.Client().Get(svr.URL + "/helloworld.Greeter/SayHello?" + query.Encode())
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	require.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+	require.Equal(t, http.MethodPost, resp.Header.Get("Allow"))
+}
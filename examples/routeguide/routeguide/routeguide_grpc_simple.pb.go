@@ -6,8 +6,6 @@ import (
 	context "context"
 	errors "errors"
 	simplegrpc "github.com/bakins/simplegrpc"
-	codes "github.com/bakins/simplegrpc/codes"
-	status "github.com/bakins/simplegrpc/status"
 )
 
 // This is a compile-time assertion to ensure that this generated file
@@ -22,7 +20,7 @@ type RouteGuideSimpleClient interface {
 	//
 	// A feature with an empty name is returned if there's no feature at the given
 	// position.
-	GetFeature(ctx context.Context, in *Point) (*Feature, error)
+	GetFeature(ctx context.Context, in *Point, opts ...simplegrpc.CallOption) (*Feature, error)
 	// A server-to-client streaming RPC.
 	//
 	// Obtains the Features available within the given Rectangle.  Results are
@@ -50,16 +48,9 @@ func NewRouteGuideSimpleClient(cc simplegrpc.ClientConn) RouteGuideSimpleClient
 	return &routeGuideSimpleClient{cc: cc}
 }
 
-func (c *routeGuideSimpleClient) GetFeature(ctx context.Context, in *Point) (*Feature, error) {
-	stream, err := c.cc.NewStream(ctx, &_RouteGuide_simple_serviceDesc.Streams[0], "/routeguide.RouteGuide/GetFeature")
-	if err != nil {
-		return nil, err
-	}
-	if err := stream.SendMsg(in); err != nil {
-		return nil, err
-	}
+func (c *routeGuideSimpleClient) GetFeature(ctx context.Context, in *Point, opts ...simplegrpc.CallOption) (*Feature, error) {
 	var out Feature
-	if err := stream.RecvMsg(&out); err != nil {
+	if err := c.cc.Invoke(ctx, "/routeguide.RouteGuide/GetFeature", in, &out, opts...); err != nil {
 		return nil, err
 	}
 	return &out, nil
@@ -95,11 +86,17 @@ func (x *routeGuideListFeaturesSimpleClient) Recv() (*Feature, error) {
 }
 
 func (c *routeGuideSimpleClient) RecordRoute(ctx context.Context) (RouteGuide_RecordRouteSimpleClient, error) {
-	return nil, status.New(codes.Unimplemented, "clients streams not currently supported")
+	stream, err := c.cc.NewStream(ctx, &_RouteGuide_simple_serviceDesc.Streams[2], "/routeguide.RouteGuide/RecordRoute")
+	if err != nil {
+		return nil, err
+	}
+	x := &routeGuideRecordRouteSimpleClient{ClientStream: stream}
+	return x, nil
 }
 
 type RouteGuide_RecordRouteSimpleClient interface {
 	Send(*Point) error
+	CloseAndRecv() (*RouteSummary, error)
 	simplegrpc.ClientStream
 }
 
@@ -111,8 +108,24 @@ func (x *routeGuideRecordRouteSimpleClient) Send(m *Point) error {
 	return x.ClientStream.SendMsg(m)
 }
 
+func (x *routeGuideRecordRouteSimpleClient) CloseAndRecv() (*RouteSummary, error) {
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	var m RouteSummary
+	if err := x.ClientStream.RecvMsg(&m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
 func (c *routeGuideSimpleClient) RouteChat(ctx context.Context) (RouteGuide_RouteChatSimpleClient, error) {
-	return nil, status.New(codes.Unimplemented, "clients streams not currently supported")
+	stream, err := c.cc.NewStream(ctx, &_RouteGuide_simple_serviceDesc.Streams[3], "/routeguide.RouteGuide/RouteChat")
+	if err != nil {
+		return nil, err
+	}
+	x := &routeGuideRouteChatSimpleClient{ClientStream: stream}
+	return x, nil
 }
 
 type RouteGuide_RouteChatSimpleClient interface {
@@ -169,20 +182,26 @@ func RegisterRouteGuideSimpleServer(s simplegrpc.ServiceRegistrar, srv RouteGuid
 	s.RegisterService(&_RouteGuide_simple_serviceDesc, srv)
 }
 
-func _RouteGuide_GetFeature_Simple_Handler(srv interface{}, stream simplegrpc.ServerStream) error {
+func _RouteGuide_GetFeature_Simple_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor simplegrpc.UnaryServerInterceptor) (interface{}, error) {
 	impl, ok := srv.(RouteGuideSimpleServer)
 	if !ok {
-		return errors.New("invalid server type - expected RouteGuideSimpleServer")
+		return nil, errors.New("invalid server type - expected RouteGuideSimpleServer")
 	}
-	var in Point
-	if err := stream.RecvMsg(&in); err != nil {
-		return err
+	in := new(Point)
+	if err := dec(in); err != nil {
+		return nil, err
 	}
-	out, err := impl.GetFeature(stream.Context(), &in)
-	if err != nil {
-		return err
+	if interceptor == nil {
+		return impl.GetFeature(ctx, in)
+	}
+	info := &simplegrpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/routeguide.RouteGuide/GetFeature",
 	}
-	return stream.SendMsg(out)
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return impl.GetFeature(ctx, req.(*Point))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
 func _RouteGuide_ListFeatures_Simple_Handler(srv interface{}, stream simplegrpc.ServerStream) error {
@@ -207,7 +226,7 @@ func (x *routeGuideListFeaturesServer) Send(m *Feature) error {
 }
 
 func _RouteGuide_RecordRoute_Simple_Handler(srv interface{}, stream simplegrpc.ServerStream) error {
-	return status.New(codes.Unimplemented, "clients streams not currently supported")
+	return srv.(RouteGuideSimpleServer).RecordRoute(&routeGuideRecordRouteServer{stream})
 }
 
 type RouteGuide_RecordRouteSimpleServer interface {
@@ -233,7 +252,7 @@ func (x *routeGuideRecordRouteServer) Recv() (*Point, error) {
 }
 
 func _RouteGuide_RouteChat_Simple_Handler(srv interface{}, stream simplegrpc.ServerStream) error {
-	return status.New(codes.Unimplemented, "clients streams not currently supported")
+	return srv.(RouteGuideSimpleServer).RouteChat(&routeGuideRouteChatServer{stream})
 }
 
 type RouteGuide_RouteChatSimpleServer interface {
@@ -263,10 +282,11 @@ var _RouteGuide_simple_serviceDesc = simplegrpc.ServiceDesc{
 	HandlerType: (*RouteGuideSimpleServer)(nil),
 	Streams: []simplegrpc.StreamDesc{
 		{
-			StreamName:    "GetFeature",
-			Handler:       _RouteGuide_GetFeature_Simple_Handler,
-			ServerStreams: false,
-			ClientStreams: false,
+			StreamName:       "GetFeature",
+			MethodHandler:    _RouteGuide_GetFeature_Simple_Handler,
+			ServerStreams:    false,
+			ClientStreams:    false,
+			IdempotencyLevel: simplegrpc.IdempotencyNoSideEffects,
 		},
 		{
 			StreamName:    "ListFeatures",
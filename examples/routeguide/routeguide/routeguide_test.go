@@ -1,18 +1,28 @@
 package routeguide
 
 import (
+	"bytes"
 	context "context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
 	"io"
+	"io/ioutil"
+	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strconv"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/require"
 	"golang.org/x/net/http2"
 	"golang.org/x/net/http2/h2c"
+	"google.golang.org/protobuf/proto"
 
 	"github.com/bakins/simplegrpc"
 	"github.com/bakins/simplegrpc/codes"
+	"github.com/bakins/simplegrpc/metadata"
 	"github.com/bakins/simplegrpc/status"
 )
 
@@ -82,6 +92,91 @@ func TestListFeatures(t *testing.T) {
 	}
 
 	require.Equal(t, 10, count)
+
+	header, err := resp.Header()
+	require.NoError(t, err)
+	require.Equal(t, []string{"testing"}, header.Get("x-name"))
+
+	require.Equal(t, []string{"10"}, resp.Trailer().Get("x-count"))
+}
+
+// tailServer waits for each feature to be received before sending the next.
+type tailServer struct {
+	server
+	received chan struct{}
+}
+
+func (s *tailServer) ListFeatures(rectangle *Rectangle, simpleServer RouteGuide_ListFeaturesSimpleServer) error {
+	for i := 0; i < 3; i++ {
+		f := Feature{
+			Name: strconv.Itoa(i),
+		}
+
+		if err := simpleServer.Send(&f); err != nil {
+			return err
+		}
+
+		select {
+		case <-s.received:
+		case <-simpleServer.Context().Done():
+			return simpleServer.Context().Err()
+		}
+	}
+
+	return nil
+}
+
+func TestListFeaturesFlush(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []simplegrpc.HandlerOption
+	}{
+		{
+			name: "default",
+		},
+		{
+			name:    "batching",
+			options: []simplegrpc.HandlerOption{simplegrpc.WithFlushBatching(100, time.Millisecond*10)},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			h := simplegrpc.NewHandler(test.options...)
+
+			srv := &tailServer{
+				received: make(chan struct{}),
+			}
+
+			RegisterRouteGuideSimpleServer(h, srv)
+
+			svr := httptest.NewServer(h2c.NewHandler(h, &http2.Server{}))
+			defer svr.Close()
+
+			conn, err := simplegrpc.NewClientConn(svr.URL)
+			require.NoError(t, err)
+
+			client := NewRouteGuideSimpleClient(conn)
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+			defer cancel()
+
+			resp, err := client.ListFeatures(ctx, &Rectangle{})
+			require.NoError(t, err)
+
+			for i := 0; i < 3; i++ {
+				f, err := resp.Recv()
+				require.NoError(t, err)
+				require.Equal(t, strconv.Itoa(i), f.Name)
+
+				srv.received <- struct{}{}
+			}
+
+			_, err = resp.Recv()
+			require.Equal(t, io.EOF, err)
+		})
+	}
 }
 
 func TestRecordRoute(t *testing.T) {
@@ -90,12 +185,22 @@ func TestRecordRoute(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
 	defer cancel()
 
-	_, err := client.RecordRoute(ctx)
-	require.Error(t, err)
+	stream, err := client.RecordRoute(ctx)
+	require.NoError(t, err)
 
-	st, ok := status.FromError(err)
-	require.True(t, ok)
-	require.Equal(t, codes.Unimplemented, st.Code())
+	for i := 0; i < 10; i++ {
+		p := Point{
+			Longitude: int32(i),
+			Latitude:  100,
+		}
+
+		require.NoError(t, stream.Send(&p))
+	}
+
+	resp, err := stream.CloseAndRecv()
+	require.NoError(t, err)
+
+	require.Equal(t, int32(10), resp.PointCount)
 }
 
 func TestRouteChat(t *testing.T) {
@@ -104,12 +209,54 @@ func TestRouteChat(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
 	defer cancel()
 
-	_, err := client.RouteChat(ctx)
+	stream, err := client.RouteChat(ctx)
+	require.NoError(t, err)
+
+	// each message is received before the next is sent
+	for i := 1; i <= 10; i++ {
+		in := RouteNote{
+			Location: &Point{
+				Latitude: int32(i),
+			},
+		}
+
+		require.NoError(t, stream.Send(&in))
+
+		out, err := stream.Recv()
+		require.NoError(t, err)
+
+		require.Equal(t, int32(i*100), out.Location.Latitude)
+	}
+
+	require.NoError(t, stream.CloseSend())
+
+	_, err = stream.Recv()
+	require.Equal(t, io.EOF, err)
+}
+
+func TestRouteChatError(t *testing.T) {
+	client := setup(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	stream, err := client.RouteChat(ctx)
+	require.NoError(t, err)
+
+	in := RouteNote{
+		Location: &Point{
+			Latitude: -1,
+		},
+	}
+
+	require.NoError(t, stream.Send(&in))
+
+	_, err = stream.Recv()
 	require.Error(t, err)
 
 	st, ok := status.FromError(err)
 	require.True(t, ok)
-	require.Equal(t, codes.Unimplemented, st.Code())
+	require.Equal(t, codes.InvalidArgument, st.Code())
 }
 
 type server struct{}
@@ -121,6 +268,10 @@ func (s *server) GetFeature(ctx context.Context, point *Point) (*Feature, error)
 }
 
 func (s *server) ListFeatures(rectangle *Rectangle, simpleServer RouteGuide_ListFeaturesSimpleServer) error {
+	if err := simpleServer.SendHeader(metadata.Pairs("x-name", "testing")); err != nil {
+		return err
+	}
+
 	f := Feature{
 		Name: "testing",
 	}
@@ -131,6 +282,8 @@ func (s *server) ListFeatures(rectangle *Rectangle, simpleServer RouteGuide_List
 		}
 	}
 
+	simpleServer.SetTrailer(metadata.Pairs("x-count", "10"))
+
 	return nil
 }
 
@@ -166,6 +319,10 @@ func (s *server) RouteChat(simpleServer RouteGuide_RouteChatSimpleServer) error
 			return err
 		}
 
+		if in.Location.Latitude < 0 {
+			return status.Error(codes.InvalidArgument, "invalid latitude")
+		}
+
 		out := RouteNote{
 			Location: &Point{
 				Latitude: in.Location.Latitude * 100,
@@ -181,3 +338,149 @@ func (s *server) RouteChat(simpleServer RouteGuide_RouteChatSimpleServer) error
 
 	return nil
 }
+
+func TestListFeaturesConnect(t *testing.T) {
+	h := simplegrpc.NewHandler()
+	RegisterRouteGuideSimpleServer(h, &server{})
+
+	svr := httptest.NewServer(h)
+	defer svr.Close()
+
+	data, err := proto.Marshal(&Rectangle{})
+	require.NoError(t, err)
+
+	body := make([]byte, 5, 5+len(data))
+	binary.BigEndian.PutUint32(body[1:], uint32(len(data)))
+	body = append(body, data...)
+
+	req, err := http.NewRequest(http.MethodPost, svr.URL+"/routeguide.RouteGuide/ListFeatures", bytes.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/connect+proto")
+
+	resp, err := svr.Client().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "application/connect+proto", resp.Header.Get("Content-Type"))
+	require.Equal(t, "testing", resp.Header.Get("x-name"))
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	count := 0
+	for len(respBody) > 0 {
+		require.True(t, len(respBody) >= 5)
+
+		flag := respBody[0]
+		length := binary.BigEndian.Uint32(respBody[1:5])
+		msg := respBody[5 : 5+length]
+		respBody = respBody[5+length:]
+
+		if flag&0x02 == 0 {
+			var f Feature
+			require.NoError(t, proto.Unmarshal(msg, &f))
+			require.Equal(t, "testing", f.Name)
+
+			count++
+
+			continue
+		}
+
+		// the end-of-stream message is last
+		require.Empty(t, respBody)
+
+		var end struct {
+			Error    json.RawMessage     `json:"error"`
+			Metadata map[string][]string `json:"metadata"`
+		}
+
+		require.NoError(t, json.Unmarshal(msg, &end))
+		require.Nil(t, end.Error)
+		require.Equal(t, []string{"10"}, end.Metadata["x-count"])
+	}
+
+	require.Equal(t, 10, count)
+}
+
+func TestGetFeatureConnectGet(t *testing.T) {
+	h := simplegrpc.NewHandler()
+	h.RegisterCodec(simplegrpc.JSONCodec)
+	RegisterRouteGuideSimpleServer(h, &server{})
+
+	svr := httptest.NewServer(h)
+	defer svr.Close()
+
+	data, err := proto.Marshal(&Point{Latitude: 100})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		path   string
+		query  url.Values
+		status int
+	}{
+		{
+			name: "proto",
+			path: "/routeguide.RouteGuide/GetFeature",
+			query: url.Values{
+				"encoding": {"proto"},
+				"base64":   {"1"},
+				"message":  {base64.RawURLEncoding.EncodeToString(data)},
+			},
+			status: http.StatusOK,
+		},
+		{
+			name: "json",
+			path: "/routeguide.RouteGuide/GetFeature",
+			query: url.Values{
+				"encoding": {"json"},
+				"message":  {`{"latitude":100}`},
+			},
+			status: http.StatusOK,
+		},
+		{
+			name: "missing encoding",
+			path: "/routeguide.RouteGuide/GetFeature",
+			query: url.Values{
+				"message": {`{"latitude":100}`},
+			},
+			status: http.StatusBadRequest,
+		},
+		{
+			name: "streaming",
+			path: "/routeguide.RouteGuide/RecordRoute",
+			query: url.Values{
+				"encoding": {"json"},
+			},
+			status: http.StatusUnsupportedMediaType,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			resp, err := svr.Client().Get(svr.URL + test.path + "?" + test.query.Encode())
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			require.Equal(t, test.status, resp.StatusCode)
+
+			if test.status != http.StatusOK {
+				return
+			}
+
+			body, err := ioutil.ReadAll(resp.Body)
+			require.NoError(t, err)
+
+			var f Feature
+			if test.query.Get("encoding") == "json" {
+				require.NoError(t, simplegrpc.JSONCodec.Unmarshal(body, &f))
+			} else {
+				require.NoError(t, proto.Unmarshal(body, &f))
+			}
+
+			require.Equal(t, "testing", f.Name)
+		})
+	}
+}
@@ -0,0 +1,320 @@
+package simplegrpc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"math"
+	"sync"
+
+	"github.com/bakins/simplegrpc/codes"
+	"github.com/bakins/simplegrpc/status"
+)
+
+// Each message is sent as a frame: a one byte flag, a four byte big endian
+// length, and then the (possibly compressed) message.
+const frameHeaderLen = 5
+
+const (
+	// flagCompressed is set when the message was compressed with the
+	// stream's compressor.
+	flagCompressed byte = 1 << 0
+	// flagEndStream is set on the end-of-stream message of Connect streaming responses.
+	flagEndStream byte = 1 << 1
+	// flagTrailer is set on the frame that holds the trailers in gRPC-Web responses.
+	flagTrailer byte = 1 << 7
+)
+
+// maxReceiveMessageSize bounds decompression for callers that do not provide a limit.
+const maxReceiveMessageSize = 1024 * 1024 * 1024 * 2
+
+// maxPooledBufferSize keeps unusually large messages from pinning memory in the pool.
+const maxPooledBufferSize = 1024 * 1024
+
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+// marshalBufferPool holds buffers for codecs that marshal into an existing buffer.
+var marshalBufferPool = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, 0, 512)
+		return &b
+	},
+}
+
+func putMarshalBuffer(b *[]byte) {
+	if cap(*b) > maxPooledBufferSize {
+		return
+	}
+
+	marshalBufferPool.Put(b)
+}
+
+func getBuffer() *bytes.Buffer {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+
+	return buf
+}
+
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBufferSize {
+		return
+	}
+
+	bufferPool.Put(buf)
+}
+
+// frameReader reads messages from a stream of frames.
+type frameReader struct {
+	reader io.Reader
+	codec  Codec
+	// encoding is the peer's grpc-encoding. It is only used for errors.
+	encoding   string
+	compressor Compressor
+	maxSize    int
+	header     [frameHeaderLen]byte
+}
+
+// readMsg reads the next frame and unmarshals it into message. It returns
+// io.EOF if the stream ended cleanly before a frame, and io.ErrUnexpectedEOF
+// if it ended within a frame.
+func (f *frameReader) readMsg(message interface{}) error {
+	if _, err := io.ReadFull(f.reader, f.header[:]); err != nil {
+		return err
+	}
+
+	flag := f.header[0]
+	switch flag {
+	case 0:
+	case flagCompressed:
+		if f.compressor == nil {
+			if f.encoding == "" || f.encoding == "identity" {
+				return status.Error(codes.Internal, "compressed flag set with identity or empty encoding")
+			}
+
+			return status.Errorf(codes.Internal, "decompressor is not installed for grpc-encoding %q", f.encoding)
+		}
+	default:
+		return status.Errorf(codes.Internal, "invalid frame flag %#x", flag)
+	}
+
+	length := binary.BigEndian.Uint32(f.header[1:])
+
+	if int64(length) > int64(f.maxSize) {
+		return status.Errorf(codes.ResourceExhausted, "received message larger than max (%d vs. %d)", length, f.maxSize)
+	}
+
+	if flag == flagCompressed {
+		if sc, ok := f.compressor.(StreamCompressor); ok {
+			return f.readCompressed(sc, int64(length), message)
+		}
+	}
+
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	buf.Grow(int(length))
+	body := buf.Bytes()[:length]
+
+	if _, err := io.ReadFull(f.reader, body); err != nil {
+		if err == io.EOF {
+			return io.ErrUnexpectedEOF
+		}
+
+		return err
+	}
+
+	if flag == flagCompressed {
+		data, err := decompress(f.compressor, body, f.maxSize)
+		if err != nil {
+			return err
+		}
+
+		body = data
+	}
+
+	return unmarshal(f.codec, body, message)
+}
+
+// readCompressed decompresses a message of length bytes as it is read.
+func (f *frameReader) readCompressed(sc StreamCompressor, length int64, message interface{}) error {
+	body := &io.LimitedReader{R: f.reader, N: length}
+
+	r, err := sc.NewReader(body)
+	if err != nil {
+		return err
+	}
+
+	if c, ok := r.(io.Closer); ok {
+		defer c.Close()
+	}
+
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	n, err := buf.ReadFrom(io.LimitReader(r, readLimit(f.maxSize)))
+	if err != nil {
+		return err
+	}
+
+	if n > int64(f.maxSize) {
+		return status.Errorf(codes.ResourceExhausted, "received message after decompression larger than max (%d)", f.maxSize)
+	}
+
+	// discard anything after the end of the compressed data so the next frame is aligned.
+	if _, err := io.Copy(ioutil.Discard, body); err != nil {
+		return err
+	}
+
+	if body.N > 0 {
+		return io.ErrUnexpectedEOF
+	}
+
+	return unmarshal(f.codec, buf.Bytes(), message)
+}
+
+// frameWriter encodes messages as frames.
+type frameWriter struct {
+	codec      Codec
+	compressor Compressor
+	maxSize    int
+	// messages smaller than minCompressSize are not compressed.
+	minCompressSize int
+}
+
+// writeMsg writes message to writer as a single frame using a single call to Write.
+func (f *frameWriter) writeMsg(writer io.Writer, message interface{}) error {
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	if err := f.appendMsg(buf, message); err != nil {
+		return err
+	}
+
+	_, err := writer.Write(buf.Bytes())
+
+	return err
+}
+
+// appendMsg appends the frame for message to buf.
+func (f *frameWriter) appendMsg(buf *bytes.Buffer, message interface{}) error {
+	var (
+		data []byte
+		err  error
+	)
+
+	if m, ok := f.codec.(appendMarshaler); ok {
+		scratch := marshalBufferPool.Get().(*[]byte)
+		defer putMarshalBuffer(scratch)
+
+		data, err = m.marshalAppend((*scratch)[:0], message)
+		if err != nil {
+			return err
+		}
+
+		*scratch = data
+	} else {
+		data, err = f.codec.Marshal(message)
+		if err != nil {
+			return err
+		}
+	}
+
+	start := buf.Len()
+
+	var header [frameHeaderLen]byte
+	_, _ = buf.Write(header[:])
+
+	compressor := f.compressor
+	if len(data) < f.minCompressSize {
+		compressor = nil
+	}
+
+	switch c := compressor.(type) {
+	case nil:
+		_, _ = buf.Write(data)
+	case StreamCompressor:
+		header[0] = flagCompressed
+
+		if err := compressTo(buf, c, data); err != nil {
+			buf.Truncate(start)
+			return err
+		}
+	default:
+		header[0] = flagCompressed
+
+		data, err = c.Compress(data)
+		if err != nil {
+			buf.Truncate(start)
+			return err
+		}
+
+		_, _ = buf.Write(data)
+	}
+
+	size := buf.Len() - start - frameHeaderLen
+	if size > f.maxSize {
+		buf.Truncate(start)
+		return status.Errorf(codes.ResourceExhausted, "trying to send message larger than max (%d vs. %d)", size, f.maxSize)
+	}
+
+	binary.BigEndian.PutUint32(header[1:], uint32(size))
+	copy(buf.Bytes()[start:], header[:])
+
+	return nil
+}
+
+func compressTo(w io.Writer, c StreamCompressor, data []byte) error {
+	z, err := c.NewWriter(w)
+	if err != nil {
+		return err
+	}
+
+	if _, err := z.Write(data); err != nil {
+		_ = z.Close()
+		return err
+	}
+
+	return z.Close()
+}
+
+// readLimit returns the number of bytes to read to detect a message larger than maxSize.
+// A maxSize of math.MaxInt64 is no limit, so one is not added to it.
+func readLimit(maxSize int) int64 {
+	limit := int64(maxSize)
+	if limit < math.MaxInt64 {
+		limit++
+	}
+
+	return limit
+}
+
+// decompress decompresses in. Stream compressors stop once more than maxSize bytes
+// have been decompressed, so a small message can not expand without bound.
+func decompress(compressor Compressor, in []byte, maxSize int) ([]byte, error) {
+	var (
+		out []byte
+		err error
+	)
+
+	if sc, ok := compressor.(StreamCompressor); ok {
+		out, err = decompressLimit(sc, in, readLimit(maxSize))
+	} else {
+		out, err = compressor.Decompress(in)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(out) > maxSize {
+		return nil, status.Errorf(codes.ResourceExhausted, "received message after decompression larger than max (%d)", maxSize)
+	}
+
+	return out, nil
+}
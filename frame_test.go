@@ -0,0 +1,324 @@
+package simplegrpc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/binary"
+	"io"
+	"math"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/bakins/simplegrpc/codes"
+	"github.com/bakins/simplegrpc/status"
+)
+
+func TestFrameRoundTrip(t *testing.T) {
+	tests := []struct {
+		name       string
+		compressor Compressor
+		maxSize    int
+	}{
+		{
+			name: "identity",
+		},
+		{
+			name:       "gzip without limit",
+			compressor: GzipCompressor,
+			maxSize:    math.MaxInt64,
+		},
+		{
+			name:       "bytes without limit",
+			compressor: bytesCompressor{GzipCompressor},
+			maxSize:    math.MaxInt64,
+		},
+		{
+			name:       "gzip",
+			compressor: GzipCompressor,
+		},
+		{
+			name:       "bytes",
+			compressor: bytesCompressor{GzipCompressor},
+		},
+		{
+			name:       "deflate",
+			compressor: DeflateCompressor,
+		},
+		{
+			name:       "gzip best speed",
+			compressor: mustCompressor(NewGzipCompressor(gzip.BestSpeed)),
+		},
+		{
+			name:       "deflate best compression",
+			compressor: mustCompressor(NewDeflateCompressor(zlib.BestCompression)),
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			w := frameWriter{codec: ProtoCodec, compressor: test.compressor, maxSize: defaultMaxSendMsgSize}
+
+			var buf bytes.Buffer
+			require.NoError(t, w.writeMsg(&buf, wrapperspb.String("hello")))
+			require.NoError(t, w.writeMsg(&buf, wrapperspb.String("")))
+			require.NoError(t, w.writeMsg(&buf, wrapperspb.String("world")))
+
+			maxSize := test.maxSize
+			if maxSize == 0 {
+				maxSize = defaultMaxRecvMsgSize
+			}
+
+			// deliver a byte at a time to simulate small HTTP/2 DATA frames.
+			r := frameReader{
+				reader:     iotest.OneByteReader(&buf),
+				codec:      ProtoCodec,
+				compressor: test.compressor,
+				maxSize:    maxSize,
+			}
+
+			for _, expected := range []string{"hello", "", "world"} {
+				var msg wrapperspb.StringValue
+				require.NoError(t, r.readMsg(&msg))
+				require.Equal(t, expected, msg.GetValue())
+			}
+
+			var msg wrapperspb.StringValue
+			require.Equal(t, io.EOF, r.readMsg(&msg))
+		})
+	}
+}
+
+func mustCompressor(c Compressor, err error) Compressor {
+	if err != nil {
+		panic(err)
+	}
+
+	return c
+}
+
+func TestCompressorLevel(t *testing.T) {
+	_, err := NewGzipCompressor(10)
+	require.Error(t, err)
+
+	_, err = NewDeflateCompressor(-3)
+	require.Error(t, err)
+}
+
+func TestFrameCompressionThreshold(t *testing.T) {
+	w := frameWriter{codec: ProtoCodec, compressor: GzipCompressor, maxSize: defaultMaxSendMsgSize, minCompressSize: 64}
+
+	var buf bytes.Buffer
+	require.NoError(t, w.writeMsg(&buf, wrapperspb.String("hello")))
+	require.Equal(t, byte(0), buf.Bytes()[0])
+
+	buf.Reset()
+	require.NoError(t, w.writeMsg(&buf, wrapperspb.String(strings.Repeat("hello", 100))))
+	require.Equal(t, flagCompressed, buf.Bytes()[0])
+}
+
+func TestFrameReaderErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       []byte
+		compressor Compressor
+		err        error
+		code       codes.Code
+	}{
+		{
+			name: "truncated header",
+			data: []byte{0, 0, 0},
+			err:  io.ErrUnexpectedEOF,
+		},
+		{
+			name: "truncated body",
+			data: []byte{0, 0, 0, 0, 4, 1, 2},
+			err:  io.ErrUnexpectedEOF,
+		},
+		{
+			name: "missing body",
+			data: []byte{0, 0, 0, 0, 4},
+			err:  io.ErrUnexpectedEOF,
+		},
+		{
+			name: "invalid flag",
+			data: []byte{2, 0, 0, 0, 0},
+			code: codes.Internal,
+		},
+		{
+			name: "compressed without compressor",
+			data: []byte{1, 0, 0, 0, 0},
+			code: codes.Internal,
+		},
+		{
+			name: "too large",
+			data: []byte{0, 0xff, 0xff, 0xff, 0xff},
+			code: codes.ResourceExhausted,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			r := frameReader{
+				reader:     bytes.NewReader(test.data),
+				codec:      ProtoCodec,
+				compressor: test.compressor,
+				maxSize:    defaultMaxRecvMsgSize,
+			}
+
+			var msg wrapperspb.StringValue
+			err := r.readMsg(&msg)
+			require.Error(t, err)
+
+			if test.err != nil {
+				require.Equal(t, test.err, err)
+				return
+			}
+
+			require.Equal(t, test.code, status.Code(err))
+		})
+	}
+}
+
+// bytesCompressor hides any StreamCompressor implementation.
+type bytesCompressor struct {
+	Compressor
+}
+
+func BenchmarkFrame(b *testing.B) {
+	benchmarks := []struct {
+		name       string
+		msg        *wrapperspb.StringValue
+		compressor Compressor
+	}{
+		{
+			name: "identity",
+			msg:  wrapperspb.String("hello world"),
+		},
+		{
+			name:       "gzip",
+			msg:        wrapperspb.String(strings.Repeat("hello world", 10000)),
+			compressor: GzipCompressor,
+		},
+		{
+			name:       "gzip bytes",
+			msg:        wrapperspb.String(strings.Repeat("hello world", 10000)),
+			compressor: bytesCompressor{GzipCompressor},
+		},
+	}
+
+	for _, bm := range benchmarks {
+		bm := bm
+		b.Run(bm.name+"/pooled", func(b *testing.B) {
+			w := frameWriter{codec: ProtoCodec, compressor: bm.compressor, maxSize: defaultMaxSendMsgSize}
+
+			var buf bytes.Buffer
+			r := frameReader{reader: &buf, codec: ProtoCodec, compressor: bm.compressor, maxSize: defaultMaxRecvMsgSize}
+
+			var out wrapperspb.StringValue
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				if err := w.writeMsg(&buf, bm.msg); err != nil {
+					b.Fatal(err)
+				}
+
+				if err := r.readMsg(&out); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+
+		b.Run(bm.name+"/unpooled", func(b *testing.B) {
+			var buf bytes.Buffer
+			var out wrapperspb.StringValue
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				if err := unpooledWriteMsg(&buf, bm.compressor, bm.msg); err != nil {
+					b.Fatal(err)
+				}
+
+				if err := unpooledReadMsg(&buf, bm.compressor, &out); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// unpooledWriteMsg writes a frame the way messages were written before frames were
+// pooled, allocating each stage of the message. It is the baseline of BenchmarkFrame.
+func unpooledWriteMsg(w io.Writer, compressor Compressor, msg interface{}) error {
+	data, err := ProtoCodec.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	prefix := []byte{0, 0, 0, 0, 0}
+
+	if compressor != nil {
+		data, err = compressor.Compress(data)
+		if err != nil {
+			return err
+		}
+
+		prefix[0] = flagCompressed
+	}
+
+	binary.BigEndian.PutUint32(prefix[1:], uint32(len(data)))
+
+	if _, err := w.Write(prefix); err != nil {
+		return err
+	}
+
+	_, err = w.Write(data)
+
+	return err
+}
+
+// unpooledReadMsg reads a frame written by unpooledWriteMsg.
+func unpooledReadMsg(r io.Reader, compressor Compressor, msg interface{}) error {
+	prefix := make([]byte, 5)
+	if _, err := io.ReadFull(r, prefix); err != nil {
+		return err
+	}
+
+	body := make([]byte, binary.BigEndian.Uint32(prefix[1:]))
+	if _, err := io.ReadFull(r, body); err != nil {
+		return err
+	}
+
+	if prefix[0] == flagCompressed {
+		data, err := compressor.Decompress(body)
+		if err != nil {
+			return err
+		}
+
+		body = data
+	}
+
+	return ProtoCodec.Unmarshal(body, msg)
+}
+
+func TestDecompressLimit(t *testing.T) {
+	compressed, err := GzipCompressor.Compress(make([]byte, 1<<20))
+	require.NoError(t, err)
+
+	_, err = decompress(GzipCompressor, compressed, 1024)
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	out, err := decompress(GzipCompressor, compressed, math.MaxInt64)
+	require.NoError(t, err)
+	require.Len(t, out, 1<<20)
+}
@@ -2,16 +2,19 @@ package simplegrpc
 
 import (
 	"context"
-	"encoding/binary"
-	"errors"
 	"fmt"
-	"io"
+	"io/ioutil"
 	"net/http"
 	"reflect"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/proto"
 
 	"github.com/bakins/simplegrpc/codes"
+	"github.com/bakins/simplegrpc/metadata"
 	"github.com/bakins/simplegrpc/status"
 )
 
@@ -29,11 +32,21 @@ type StreamServerInterceptor func(srv interface{}, ss ServerStream, info *Stream
 
 // Handler is an HTTP handler for grpc services
 type Handler struct {
-	methodHandlers map[string]*method
-	services       map[string]*service
-	codecs         map[string]Codec
-	compressors    map[string]Compressor
-	interceptor    StreamServerInterceptor
+	methodHandlers   map[string]*method
+	services         map[string]*service
+	codecs           map[string]Codec
+	compressors      map[string]Compressor
+	acceptEncoding   string
+	preference       []string
+	interceptor      StreamServerInterceptor
+	unaryInterceptor UnaryServerInterceptor
+	maxRecvMsgSize   int
+	maxSendMsgSize   int
+	minCompressSize  int
+	flushMessages    int
+	flushInterval    time.Duration
+	allowOrigin      func(origin string) bool
+	httpRoutes       []*httpRoute
 }
 
 type service struct {
@@ -42,6 +55,8 @@ type service struct {
 }
 
 type method struct {
+	// fullMethod is the gRPC path of the method, such as /package.Service/Method.
+	fullMethod string
 	streamDesc StreamDesc
 	server     interface{}
 }
@@ -63,14 +78,135 @@ type MethodInfo struct {
 	IsServerStream bool
 }
 
-// NewHandler creates a new handler. Only protobuff codec is registered.
-func NewHandler() *Handler {
-	h := &Handler{}
-	h.RegisterCodec(ProtoCodec)
+type handlerOptions struct {
+	streamInterceptor       StreamServerInterceptor
+	chainStreamInterceptors []StreamServerInterceptor
+	unaryInterceptor        UnaryServerInterceptor
+	chainUnaryInterceptors  []UnaryServerInterceptor
+	flushMessages           int
+	flushInterval           time.Duration
+	preference              []string
+	allowOrigin             func(origin string) bool
+	shared                  sharedOptions
+}
+
+// HandlerOption configures a Handler.
+type HandlerOption interface {
+	applyHandler(*handlerOptions)
+}
+
+type handlerOptionFunc func(*handlerOptions)
+
+func (f handlerOptionFunc) applyHandler(o *handlerOptions) {
+	f(o)
+}
+
+// WithStreamInterceptor sets the stream interceptor. It is run for every method,
+// before any interceptors added by WithChainStreamInterceptor.
+func WithStreamInterceptor(interceptor StreamServerInterceptor) HandlerOption {
+	return handlerOptionFunc(func(o *handlerOptions) {
+		o.streamInterceptor = interceptor
+	})
+}
+
+// WithChainStreamInterceptor adds stream interceptors. The first interceptor is
+// the outermost, and the last is the innermost wrapper around the method handler.
+// It may be used multiple times.
+func WithChainStreamInterceptor(interceptors ...StreamServerInterceptor) HandlerOption {
+	return handlerOptionFunc(func(o *handlerOptions) {
+		o.chainStreamInterceptors = append(o.chainStreamInterceptors, interceptors...)
+	})
+}
+
+// WithUnaryInterceptor sets the unary interceptor. It is run for unary methods after
+// all stream interceptors, and before any interceptors added by WithChainUnaryInterceptor.
+func WithUnaryInterceptor(interceptor UnaryServerInterceptor) HandlerOption {
+	return handlerOptionFunc(func(o *handlerOptions) {
+		o.unaryInterceptor = interceptor
+	})
+}
+
+// WithChainUnaryInterceptor adds unary interceptors. The first interceptor is
+// the outermost, and the last is the innermost wrapper around the method implementation.
+// It may be used multiple times.
+func WithChainUnaryInterceptor(interceptors ...UnaryServerInterceptor) HandlerOption {
+	return handlerOptionFunc(func(o *handlerOptions) {
+		o.chainUnaryInterceptors = append(o.chainUnaryInterceptors, interceptors...)
+	})
+}
+
+// WithFlushBatching coalesces flushes of streamed responses. The response is flushed
+// once messages messages are pending, or interval after the first pending message was
+// sent, whichever is first. A zero value disables that trigger. Pending messages are
+// always sent when the method returns.
+// By default, the response is flushed after every message.
+func WithFlushBatching(messages int, interval time.Duration) HandlerOption {
+	return handlerOptionFunc(func(o *handlerOptions) {
+		o.flushMessages = messages
+		o.flushInterval = interval
+	})
+}
+
+// WithCompressorPreference sets the names of the compressors to use for responses, most
+// preferred first. The first registered compressor the client accepts is used, regardless of
+// how the request was compressed, and responses are not compressed if there is none.
+// By default, responses use the compressor of the request if the client accepts it.
+func WithCompressorPreference(names ...string) HandlerOption {
+	return handlerOptionFunc(func(o *handlerOptions) {
+		o.preference = names
+	})
+}
+
+// WithCORS allows cross-origin gRPC-Web requests from browsers. Requests, including
+// preflight requests, are allowed when allowOrigin returns true for their origin.
+// By default, no cross-origin requests are allowed.
+func WithCORS(allowOrigin func(origin string) bool) HandlerOption {
+	return handlerOptionFunc(func(o *handlerOptions) {
+		o.allowOrigin = allowOrigin
+	})
+}
+
+// NewHandler creates a new handler. It uses the codecs and compressors registered
+// with RegisterCodec and RegisterCompressor.
+func NewHandler(options ...HandlerOption) *Handler {
+	opts := handlerOptions{
+		shared: defaultSharedOptions(),
+	}
+	for _, o := range options {
+		o.applyHandler(&opts)
+	}
+
+	streamInterceptors := opts.chainStreamInterceptors
+	if opts.streamInterceptor != nil {
+		streamInterceptors = append([]StreamServerInterceptor{opts.streamInterceptor}, streamInterceptors...)
+	}
+
+	unaryInterceptors := opts.chainUnaryInterceptors
+	if opts.unaryInterceptor != nil {
+		unaryInterceptors = append([]UnaryServerInterceptor{opts.unaryInterceptor}, unaryInterceptors...)
+	}
+
+	h := &Handler{
+		interceptor:      chainStreamServerInterceptors(streamInterceptors),
+		unaryInterceptor: chainUnaryServerInterceptors(unaryInterceptors),
+		maxRecvMsgSize:   opts.shared.maxRecvMsgSize,
+		maxSendMsgSize:   opts.shared.maxSendMsgSize,
+		minCompressSize:  opts.shared.compressionThreshold,
+		flushMessages:    opts.flushMessages,
+		flushInterval:    opts.flushInterval,
+		preference:       opts.preference,
+		allowOrigin:      opts.allowOrigin,
+	}
+
+	h.codecs = registeredCodecs()
+	h.compressors = registeredCompressors()
+	h.acceptEncoding = acceptEncodingHeader(h.compressors)
+
 	return h
 }
 
-// RegisterCodec registers a codec. This must be called before the handler takes requests
+// RegisterCodec registers a codec for this handler only, replacing any codec with the same name.
+// This must be called before the handler takes requests
 func (h *Handler) RegisterCodec(codec Codec) {
 	if h.codecs == nil {
 		h.codecs = make(map[string]Codec)
@@ -79,13 +215,15 @@ func (h *Handler) RegisterCodec(codec Codec) {
 	h.codecs[codec.Name()] = codec
 }
 
-// RegisterCompressor registers a Compressor. This must be called before the handler takes requests
+// RegisterCompressor registers a Compressor for this handler only, replacing any compressor with the same name.
+// This must be called before the handler takes requests
 func (h *Handler) RegisterCompressor(compressor Compressor) {
 	if h.compressors == nil {
 		h.compressors = make(map[string]Compressor)
 	}
 
 	h.compressors[compressor.Name()] = compressor
+	h.acceptEncoding = acceptEncodingHeader(h.compressors)
 }
 
 // GetServiceInfo returns a map from service names to ServiceInfo.
@@ -94,7 +232,9 @@ func (h *Handler) GetServiceInfo() map[string]ServiceInfo {
 	out := make(map[string]ServiceInfo)
 
 	for _, s := range h.services {
-		info := ServiceInfo{}
+		info := ServiceInfo{
+			Metadata: s.serviceDesc.Metadata,
+		}
 
 		for _, m := range s.methods {
 			mi := MethodInfo{
@@ -110,72 +250,188 @@ func (h *Handler) GetServiceInfo() map[string]ServiceInfo {
 	return out
 }
 
-// ServeHTP ...
+// ServeHTTP serves gRPC, gRPC-Web and Connect requests, and REST requests that
+// match the HTTP rules of a method.
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	codec, err := h.getCodec(r.Header.Get("Content-Type"))
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if isPreflight(r) {
+		h.servePreflight(w, r)
 		return
 	}
 
-	w.Header().Add("Trailer", "grpc-status, grpc-message")
+	protocol, codec := protocolHTTP, h.jsonCodec()
 
-	compressor, err := h.getCompressor(r.Header.Get("Grpc-Encoding"))
-	if err != nil {
-		for k := range h.compressors {
-			w.Header().Add("grpc-accept-encoding", k)
+	binding := h.matchHTTPRoute(r)
+	if binding == nil {
+		if r.Method == http.MethodGet {
+			if err := connectGetRequest(r); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
 		}
 
-		w.WriteHeader(http.StatusOK)
-		statusTrailer(w, err)
+		var err error
+
+		protocol, codec, err = h.getCodec(r.Header.Get("Content-Type"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if r.Method == http.MethodGet && protocol != protocolConnectUnary {
+			http.Error(w, "unsupported encoding for GET requests", http.StatusBadRequest)
+			return
+		}
+	}
 
+	cors := h.setCORSHeaders(w, r)
+
+	switch protocol {
+	case protocolGRPC:
+		w.Header().Add("Trailer", "grpc-status, grpc-message, grpc-status-details-bin")
+	case protocolGRPCWebText:
+		r.Body = ioutil.NopCloser(newBase64Reader(r.Body))
+		w = base64ResponseWriter{w}
+	}
+
+	if h.acceptEncoding != "" {
+		w.Header().Set(protocol.acceptEncodingHeader(), h.acceptEncoding)
+	}
+
+	requestCompressor, err := h.getCompressor(r.Header.Get(protocol.encodingHeader()))
+	if err != nil {
+		errorResponse(w, protocol, err)
 		return
 	}
 
-	if compressor != nil {
-		w.Header().Set("Grpc-Encoding", compressor.Name())
+	responseCompressor := h.responseCompressor(r.Header, protocol, requestCompressor)
+	if responseCompressor != nil {
+		w.Header().Set(protocol.encodingHeader(), responseCompressor.Name())
 	}
 
-	w.Header().Set("Content-Type", baseContentType+"+"+codec.Name())
-	w.WriteHeader(http.StatusOK)
+	w.Header().Set("Content-Type", protocol.responseContentType(codec))
 
 	m, ok := h.methodHandlers[r.URL.Path]
+	if binding != nil {
+		m, ok = binding.route.method, true
+	}
+
 	if !ok {
 		err := status.Errorf(codes.Unimplemented, "service method %q is not implemented by this server", r.URL.Path)
-		statusTrailer(w, err)
+		errorResponse(w, protocol, err)
 
 		return
 	}
 
-	ctx, cancel := context.WithCancel(r.Context())
+	if protocol.isUnary() && (m.streamDesc.ClientStreams || m.streamDesc.ServerStreams) {
+		http.Error(w, "streaming methods require a streaming content-type", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	if r.Method == http.MethodGet && protocol == protocolConnectUnary && m.streamDesc.IdempotencyLevel != IdempotencyNoSideEffects {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method has side effects and requires POST", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	ctx, cancel, err := contextWithTimeout(r, protocol)
+	if err != nil {
+		errorResponse(w, protocol, err)
+		return
+	}
 	defer cancel()
 
+	md, err := metadataFromHeader(r.Header)
+	if err != nil {
+		errorResponse(w, protocol, status.Errorf(codes.Internal, "malformed metadata: %v", err))
+		return
+	}
+
+	ctx = metadata.NewIncomingContext(ctx, md)
+
 	r = r.WithContext(ctx)
 
-	stream, err := h.newServerStream(w, r, codec, compressor)
+	stream, err := h.newServerStream(w, r, &m.streamDesc, protocol, codec, requestCompressor, responseCompressor)
+	stream.exposeHeaders = cors
+	stream.binding = binding
 
 	if h.interceptor == nil {
 		err = m.streamDesc.Handler(m.server, stream)
-		statusTrailer(w, err)
+	} else {
+		// func(srv interface{}, ss ServerStream, info *StreamServerInfo, handler StreamHandler) error
+		info := StreamServerInfo{
+			FullMethod:     m.fullMethod,
+			IsClientStream: m.streamDesc.ClientStreams,
+			IsServerStream: m.streamDesc.ServerStreams,
+		}
+
+		err = h.interceptor(m.server, stream, &info, m.streamDesc.Handler)
+	}
+
+	if ctx.Err() == context.DeadlineExceeded {
+		err = status.Error(codes.DeadlineExceeded, "deadline exceeded")
+	}
+
+	stream.writeStatus(err)
+}
+
+// errorResponse writes a response that contains only a status.
+func errorResponse(w http.ResponseWriter, protocol protocol, err error) {
+	if protocol.isUnary() {
+		writeConnectError(w, err)
 		return
 	}
 
-	// func(srv interface{}, ss ServerStream, info *StreamServerInfo, handler StreamHandler) error
-	info := StreamServerInfo{
-		FullMethod:     r.URL.Path,
-		IsClientStream: m.streamDesc.ClientStreams,
-		IsServerStream: m.streamDesc.ServerStreams,
+	w.WriteHeader(http.StatusOK)
+
+	switch protocol {
+	case protocolConnectStream:
+		_ = writeConnectEndStream(w, nil, err)
+	case protocolGRPCWeb, protocolGRPCWebText:
+		_ = writeWebTrailer(w, statusHeader(err))
+	default:
+		statusTrailer(w, err)
+	}
+}
+
+// contextWithTimeout returns a context for the request that is canceled
+// once the timeout sent by the client, if any, has elapsed.
+func contextWithTimeout(r *http.Request, protocol protocol) (context.Context, context.CancelFunc, error) {
+	v, decode := r.Header.Get("Grpc-Timeout"), decodeTimeout
+	if protocol.isConnect() {
+		v, decode = r.Header.Get("Connect-Timeout-Ms"), decodeConnectTimeout
+	}
+
+	if v == "" {
+		ctx, cancel := context.WithCancel(r.Context())
+		return ctx, cancel, nil
 	}
 
-	err = h.interceptor(m.server, stream, &info, m.streamDesc.Handler)
-	statusTrailer(w, err)
+	timeout, err := decode(v)
+	if err != nil {
+		return nil, nil, status.Errorf(codes.Internal, "malformed timeout: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	return ctx, cancel, nil
 }
 
 // assumes WriteHeader has allready been called
 func statusTrailer(w http.ResponseWriter, err error) {
+	for k, v := range statusHeader(err) {
+		w.Header()[k] = v
+	}
+}
+
+// statusHeader returns the status headers for err.
+func statusHeader(err error) http.Header {
+	header := make(http.Header)
+
 	if err == nil {
-		w.Header().Set("grpc-status", "0")
-		w.Header().Set("grpc-message", "OK")
+		header.Set("grpc-status", "0")
+		header.Set("grpc-message", "OK")
+
+		return header
 	}
 
 	st, ok := status.FromError(err)
@@ -195,8 +451,17 @@ func statusTrailer(w http.ResponseWriter, err error) {
 		}
 	}
 
-	w.Header().Set("grpc-status", code)
-	w.Header().Set("grpc-message", message)
+	header.Set("grpc-status", code)
+	header.Set("grpc-message", encodeGrpcMessage(message))
+
+	if p := st.Proto(); ok && len(p.GetDetails()) > 0 {
+		// details are only sent when the status was created by the status package
+		if data, err := proto.Marshal(p); err == nil {
+			header.Set("grpc-status-details-bin", encodeMetadataValue("grpc-status-details-bin", string(data)))
+		}
+	}
+
+	return header
 }
 
 // RegisterService registers a service and its implementation to the gRPC
@@ -235,7 +500,12 @@ func (h *Handler) RegisterService(sd *ServiceDesc, ss interface{}) {
 			panic(fmt.Sprintf("grpc: RegisterService found duplicate method registration for %q", fullMethod))
 		}
 
+		if m.MethodHandler != nil {
+			m.Handler = h.unaryStreamHandler(m.MethodHandler)
+		}
+
 		mth := &method{
+			fullMethod: fullMethod,
 			streamDesc: m,
 			server:     ss,
 		}
@@ -243,6 +513,10 @@ func (h *Handler) RegisterService(sd *ServiceDesc, ss interface{}) {
 		svc.methods = append(svc.methods, mth)
 
 		h.methodHandlers[fullMethod] = mth
+
+		for _, rule := range m.HTTPRules {
+			h.addHTTPRoute(rule, mth)
+		}
 	}
 }
 
@@ -259,157 +533,457 @@ func (h *Handler) getCompressor(accept string) (Compressor, error) {
 	return c, nil
 }
 
-func (h *Handler) getCodec(contentType string) (Codec, error) {
-	subType := contentSubtype(contentType)
+// responseCompressor chooses the compressor for the response. It returns nil
+// if the response should not be compressed.
+func (h *Handler) responseCompressor(header http.Header, protocol protocol, requestCompressor Compressor) Compressor {
+	accepted := acceptedEncodings(header, protocol.acceptEncodingHeader())
+
+	if len(h.preference) == 0 {
+		// a client that did not advertise what it accepts can decode what it sent.
+		if requestCompressor == nil || (accepted != nil && !accepted[requestCompressor.Name()]) {
+			return nil
+		}
+
+		return requestCompressor
+	}
+
+	for _, name := range h.preference {
+		if c, ok := h.compressors[name]; ok && accepted[name] {
+			return c
+		}
+	}
+
+	return nil
+}
+
+func (h *Handler) getCodec(contentType string) (protocol, Codec, error) {
+	protocol, subType := parseContentType(contentType)
 	if subType == "" {
-		return nil, fmt.Errorf("unsupported content-type %q", contentType)
+		return protocol, nil, fmt.Errorf("unsupported content-type %q", contentType)
 	}
 
 	codec, ok := h.codecs[subType]
 	if !ok || codec == nil {
-		return nil, fmt.Errorf("unsupported sub-content-type in %q", contentType)
+		return protocol, nil, fmt.Errorf("unsupported sub-content-type in %q", contentType)
 	}
 
-	return codec, nil
+	return protocol, codec, nil
 }
 
 type serverStream struct {
-	ctx        context.Context
-	reader     io.ReadCloser
-	writer     io.Writer
-	codec      Codec
-	compressor Compressor
+	ctx         context.Context
+	reader      frameReader
+	writer      http.ResponseWriter
+	frameWriter frameWriter
+	protocol    protocol
+	header      metadata.MD
+	trailer     metadata.MD
+	headerSent  bool
+	// exposeHeaders is set when browsers must be allowed to read the response headers.
+	exposeHeaders bool
+	// unaryReceived and unaryResponse are used for Connect unary and transcoded requests.
+	unaryReceived bool
+	unaryResponse []byte
+	// binding is the matched HTTP rule of transcoded requests.
+	binding *httpBinding
+
+	// mu guards writes to the response, as a batched flush may run
+	// on the timer's goroutine.
+	mu            sync.Mutex
+	flusher       http.Flusher
+	flushMessages int
+	flushInterval time.Duration
+	pending       int
+	flushTimer    *time.Timer
+	done          bool
 }
 
-func (h *Handler) newServerStream(w http.ResponseWriter, r *http.Request, codec Codec, compressor Compressor) (*serverStream, error) {
-	// TODO: compression
-	return &serverStream{
-		ctx:        r.Context(),
-		reader:     r.Body,
-		writer:     w,
-		codec:      codec,
-		compressor: compressor,
-	}, nil
+type serverStreamKey struct{}
+
+func (h *Handler) newServerStream(w http.ResponseWriter, r *http.Request, desc *StreamDesc, protocol protocol, codec Codec, requestCompressor, responseCompressor Compressor) (*serverStream, error) {
+	s := &serverStream{
+		protocol: protocol,
+		reader: frameReader{
+			reader:     r.Body,
+			codec:      codec,
+			compressor: requestCompressor,
+			maxSize:    h.maxRecvMsgSize,
+		},
+		writer: w,
+		frameWriter: frameWriter{
+			codec:           codec,
+			compressor:      responseCompressor,
+			maxSize:         h.maxSendMsgSize,
+			minCompressSize: h.minCompressSize,
+		},
+	}
+
+	s.ctx = context.WithValue(r.Context(), serverStreamKey{}, s)
+
+	// streamed messages must reach the client as they are sent rather than
+	// when the method returns.
+	if desc.ServerStreams {
+		if f, ok := w.(http.Flusher); ok {
+			s.flusher = f
+			s.flushMessages = h.flushMessages
+			s.flushInterval = h.flushInterval
+		}
+	}
+
+	return s, nil
 }
 
 func (s *serverStream) Context() context.Context {
 	return s.ctx
 }
 
-const maxReceiveMessageSize = 1024 * 1024 * 1024 * 2
+var errHeaderSent = status.Error(codes.Internal, "headers have already been sent")
+
+// SetHeader sets the header metadata. It may be called multiple times.
+// When call multiple times, all the provided metadata will be merged.
+// The metadata is sent when SendHeader is called or the first message is sent.
+func (s *serverStream) SetHeader(md metadata.MD) error {
+	if s.headerSent {
+		return errHeaderSent
+	}
+
+	s.header = metadata.Join(s.header, md)
 
-func recvMsg(reader io.Reader, codec Codec, compressor Compressor, message interface{}) error {
-	prefix := []byte{0, 0, 0, 0, 0}
+	return nil
+}
 
-	if _, err := reader.Read(prefix); err != nil {
-		fmt.Println("read failed", err)
-		// EOF here means end of stream
+// SendHeader sends the header metadata, merged with any metadata previously set
+// by SetHeader. It fails if called multiple times.
+// For Connect unary and transcoded requests, the headers are sent with the response.
+func (s *serverStream) SendHeader(md metadata.MD) error {
+	if err := s.SetHeader(md); err != nil {
 		return err
 	}
 
-	length := binary.BigEndian.Uint32(prefix[1:])
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	var body []byte
+	// the headers of unary protocols are held until the status is known, but
+	// are not changed after this.
+	if s.protocol.isUnary() {
+		s.headerSent = true
+		return nil
+	}
 
-	// TODO: handle maximum message size
-	if length > 0 {
-		body = make([]byte, length)
+	s.writeHeader()
 
-		if n, err := reader.Read(body); err != nil {
-			// if is EOF and we read it all, it is fine.  next read will return EOF
-			if err != io.EOF {
-				return err
-			}
+	if f, ok := s.writer.(http.Flusher); ok {
+		s.flush(f)
+	}
 
-			if n != int(length) {
-				return errors.New("unexpected EOF")
-			}
-		}
+	return nil
+}
+
+// SetTrailer sets the trailer metadata which will be sent with the RPC status.
+// When called more than once, all the provided metadata will be merged.
+func (s *serverStream) SetTrailer(md metadata.MD) {
+	s.trailer = metadata.Join(s.trailer, md)
+}
+
+func (s *serverStream) writeHeader() {
+	if s.headerSent {
+		return
 	}
 
-	// todo compress check should be a bit check
-	if compressor != nil && prefix[0] == 1 {
-		data, err := decompress(compressor, body)
-		if err != nil {
-			return err
+	s.headerSent = true
+
+	setMetadataHeader(s.writer.Header(), s.header)
+
+	if s.exposeHeaders {
+		expose := append([]string{}, grpcWebExposeHeaders...)
+		for k := range s.header {
+			expose = append(expose, k)
 		}
 
-		body = data
+		s.writer.Header().Set("Access-Control-Expose-Headers", strings.Join(expose, ", "))
 	}
 
-	return codec.Unmarshal(body, message)
+	s.writer.WriteHeader(http.StatusOK)
 }
 
-func (s *serverStream) RecvMsg(m interface{}) error {
-	return recvMsg(s.reader, s.codec, s.compressor, m)
+// writeStatus sends the trailer metadata and the status. The headers are
+// sent first if no message was sent.
+func (s *serverStream) writeStatus(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.done = true
+	if s.flushTimer != nil {
+		s.flushTimer.Stop()
+	}
+
+	if s.protocol.isUnary() {
+		s.writeConnectUnary(err)
+		return
+	}
+
+	s.writeHeader()
+
+	trailer := make(http.Header)
+	setMetadataHeader(trailer, s.trailer)
+
+	switch s.protocol {
+	case protocolConnectStream:
+		_ = writeConnectEndStream(s.writer, trailer, err)
+		return
+	case protocolGRPCWeb, protocolGRPCWebText:
+		for k, v := range statusHeader(err) {
+			trailer[k] = v
+		}
+
+		_ = writeWebTrailer(s.writer, trailer)
+
+		return
+	}
+
+	// trailers that were not announced before the headers were sent must use the prefix
+	for k, vv := range trailer {
+		for _, v := range vv {
+			s.writer.Header().Add(http.TrailerPrefix+k, v)
+		}
+	}
+
+	statusTrailer(s.writer, err)
 }
 
-func decompress(compressor Compressor, in []byte) ([]byte, error) {
-	if compressor == nil {
-		return in, nil
+// SetHeader sets the header metadata to be sent from the server to the client.
+// The context provided must be the context passed to the server's handler.
+func SetHeader(ctx context.Context, md metadata.MD) error {
+	s, err := serverStreamFromContext(ctx)
+	if err != nil {
+		return err
 	}
 
-	return compressor.Decompress(in)
+	return s.SetHeader(md)
 }
 
-func sendMsg(writer io.Writer, codec Codec, compressor Compressor, message interface{}) error {
-	data, err := codec.Marshal(message)
+// SendHeader sends header metadata. It may be called at most once.
+// The context provided must be the context passed to the server's handler.
+func SendHeader(ctx context.Context, md metadata.MD) error {
+	s, err := serverStreamFromContext(ctx)
 	if err != nil {
 		return err
 	}
 
-	data, err = compress(compressor, data)
+	return s.SendHeader(md)
+}
+
+// SetTrailer sets the trailer metadata that will be sent when an RPC returns.
+// The context provided must be the context passed to the server's handler.
+func SetTrailer(ctx context.Context, md metadata.MD) error {
+	s, err := serverStreamFromContext(ctx)
 	if err != nil {
 		return err
 	}
-	prefix := []byte{0, 0, 0, 0, 0}
 
-	// TODO should be a bit flag
+	s.SetTrailer(md)
 
-	if compressor != nil {
-		prefix[0] = 1
+	return nil
+}
+
+func serverStreamFromContext(ctx context.Context) (*serverStream, error) {
+	s, ok := ctx.Value(serverStreamKey{}).(*serverStream)
+	if !ok {
+		return nil, status.Error(codes.Internal, "failed to fetch the stream from the context")
 	}
 
-	binary.BigEndian.PutUint32(prefix[1:], uint32(len(data)))
+	return s, nil
+}
 
-	if _, err = writer.Write(prefix); err != nil {
-		return err
+func (s *serverStream) RecvMsg(m interface{}) error {
+	switch s.protocol {
+	case protocolConnectUnary:
+		return s.recvConnectUnary(m)
+	case protocolHTTP:
+		return s.recvHTTP(m)
 	}
 
-	_, err = writer.Write(data)
-	return err
+	return s.reader.readMsg(m)
 }
 
 func (s *serverStream) SendMsg(m interface{}) error {
-	return sendMsg(s.writer, s.codec, s.compressor, m)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch s.protocol {
+	case protocolConnectUnary:
+		return s.sendConnectUnary(m)
+	case protocolHTTP:
+		return s.sendHTTP(m)
+	}
+
+	s.writeHeader()
+
+	if err := s.frameWriter.writeMsg(s.writer, m); err != nil {
+		return err
+	}
+
+	if s.flusher == nil {
+		return nil
+	}
+
+	s.pending++
+
+	switch {
+	case s.flushMessages <= 0 && s.flushInterval <= 0:
+		s.flush(s.flusher)
+	case s.flushMessages > 0 && s.pending >= s.flushMessages:
+		s.flush(s.flusher)
+	case s.flushInterval > 0 && s.flushTimer == nil:
+		s.flushTimer = time.AfterFunc(s.flushInterval, s.flushPending)
+	}
+
+	return nil
 }
 
-func compress(compressor Compressor, in []byte) ([]byte, error) {
-	if compressor == nil {
-		return in, nil
+// flush must be called with mu held.
+func (s *serverStream) flush(f http.Flusher) {
+	s.pending = 0
+
+	if s.flushTimer != nil {
+		s.flushTimer.Stop()
+		s.flushTimer = nil
+	}
+
+	f.Flush()
+}
+
+func (s *serverStream) flushPending() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// the response may not be used once the method has returned.
+	if s.done || s.pending == 0 {
+		return
 	}
 
-	return compressor.Compress(in)
+	s.flush(s.flusher)
 }
 
 const baseContentType = "application/grpc"
 
-func contentSubtype(contentType string) string {
-	if contentType == baseContentType {
+// protocol is the protocol of a request.
+type protocol int
+
+const (
+	protocolGRPC protocol = iota
+	protocolGRPCWeb
+	protocolGRPCWebText
+	protocolConnectUnary
+	protocolConnectStream
+	// protocolHTTP is a REST request, transcoded using the HTTP rules of a method.
+	protocolHTTP
+)
+
+// contentType returns the content type of the protocol, without a subtype.
+func (p protocol) contentType() string {
+	switch p {
+	case protocolGRPCWeb:
+		return grpcWebContentType
+	case protocolGRPCWebText:
+		return grpcWebTextContentType
+	default:
+		return baseContentType
+	}
+}
+
+// responseContentType returns the content type of responses encoded with codec.
+func (p protocol) responseContentType(codec Codec) string {
+	switch p {
+	case protocolConnectUnary, protocolHTTP:
+		return connectUnaryContentTypePrefix + codec.Name()
+	case protocolConnectStream:
+		return connectStreamContentTypePrefix + codec.Name()
+	default:
+		return p.contentType() + "+" + codec.Name()
+	}
+}
+
+func (p protocol) isConnect() bool {
+	return p == protocolConnectUnary || p == protocolConnectStream
+}
+
+// isUnary reports whether the response is a single message, sent once the status is known.
+func (p protocol) isUnary() bool {
+	return p == protocolConnectUnary || p == protocolHTTP
+}
+
+// encodingHeader returns the header that holds the name of the compressor used for messages.
+func (p protocol) encodingHeader() string {
+	switch p {
+	case protocolConnectUnary, protocolHTTP:
+		return "Content-Encoding"
+	case protocolConnectStream:
+		return "Connect-Content-Encoding"
+	default:
+		return "Grpc-Encoding"
+	}
+}
+
+// acceptEncodingHeader returns the header that lists the compressors a peer accepts.
+func (p protocol) acceptEncodingHeader() string {
+	switch p {
+	case protocolConnectUnary, protocolHTTP:
+		return "Accept-Encoding"
+	case protocolConnectStream:
+		return "Connect-Accept-Encoding"
+	default:
+		return "Grpc-Accept-Encoding"
+	}
+}
+
+// parseContentType returns the protocol and content subtype of a request.
+// The subtype is empty if the content type is not supported.
+func parseContentType(contentType string) (protocol, string) {
+	for _, p := range []protocol{protocolGRPC, protocolGRPCWeb, protocolGRPCWebText} {
+		if subType := contentSubtype(contentType, p.contentType()); subType != "" {
+			return p, subType
+		}
+	}
+
+	if strings.HasPrefix(contentType, connectStreamContentTypePrefix) {
+		return protocolConnectStream, contentType[len(connectStreamContentTypePrefix):]
+	}
+
+	if strings.HasPrefix(contentType, connectUnaryContentTypePrefix) {
+		subType := contentType[len(connectUnaryContentTypePrefix):]
+
+		// parameters, such as charset=utf-8 for JSON, are ignored
+		if i := strings.IndexByte(subType, ';'); i >= 0 {
+			subType = strings.TrimSpace(subType[:i])
+		}
+
+		if strings.HasPrefix(subType, "grpc") || strings.HasPrefix(subType, "connect") {
+			return protocolGRPC, ""
+		}
+
+		return protocolConnectUnary, subType
+	}
+
+	return protocolGRPC, ""
+}
+
+func contentSubtype(contentType string, base string) string {
+	if contentType == base {
 		return "proto"
 	}
 
-	if !strings.HasPrefix(contentType, baseContentType) {
+	if !strings.HasPrefix(contentType, base) {
 		return ""
 	}
 
-	// guaranteed since != baseContentType and has baseContentType prefix
-	switch contentType[len(baseContentType)] {
+	// guaranteed since != base and has base prefix
+	switch contentType[len(base)] {
 	case '+', ';':
 		// this will return true for "application/grpc+" or "application/grpc;"
 		// which the previous validContentType function tested to be valid, so we
 		// just say that no content-subtype is specified in this case
-		return contentType[len(baseContentType)+1:]
+		return contentType[len(base)+1:]
 	default:
 		return ""
 	}
@@ -425,12 +999,31 @@ type ServiceDesc struct {
 
 // StreamDesc represents a streaming RPC service's method specification.
 type StreamDesc struct {
-	StreamName    string
-	Handler       StreamHandler
+	StreamName string
+	Handler    StreamHandler
+	// MethodHandler is set for unary methods. If set, Handler is ignored.
+	MethodHandler MethodHandler
 	ServerStreams bool
 	ClientStreams bool
+	// IdempotencyLevel is set from the idempotency_level method option.
+	IdempotencyLevel IdempotencyLevel
+	// HTTPRules are set from the google.api.http method option.
+	HTTPRules []HTTPRule
 }
 
+// IdempotencyLevel is the idempotency of a method, as declared by its idempotency_level option.
+type IdempotencyLevel int
+
+const (
+	// IdempotencyUnknown methods may have side effects. It is the default.
+	IdempotencyUnknown IdempotencyLevel = iota
+	// IdempotencyNoSideEffects methods can be called with HTTP GET, so responses may be cached.
+	IdempotencyNoSideEffects
+	// IdempotencyIdempotent methods may have side effects, but calling them more than
+	// once has the same effect as calling them once.
+	IdempotencyIdempotent
+)
+
 // StreamServerInfo ...
 type StreamServerInfo struct {
 	FullMethod     string
@@ -443,6 +1036,15 @@ type StreamHandler func(srv interface{}, stream ServerStream) error
 
 // ServerStream ...
 type ServerStream interface {
+	// SetHeader sets the header metadata. It may be called multiple times.
+	// It fails if called after the headers have been sent.
+	SetHeader(metadata.MD) error
+	// SendHeader sends the header metadata, merged with any metadata set by
+	// SetHeader. It fails if called multiple times.
+	SendHeader(metadata.MD) error
+	// SetTrailer sets the trailer metadata which will be sent with the RPC status.
+	// When called more than once, all the provided metadata will be merged.
+	SetTrailer(metadata.MD)
 	Context() context.Context
 	SendMsg(m interface{}) error
 	RecvMsg(m interface{}) error
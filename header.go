@@ -0,0 +1,195 @@
+package simplegrpc
+
+import (
+	"encoding/base64"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bakins/simplegrpc/metadata"
+)
+
+const binHdrSuffix = "-bin"
+
+// isReservedHeader checks whether hdr belongs to HTTP2 headers
+// reserved by gRPC protocol or managed by the HTTP transport. Any other
+// headers are classified as the user-specified metadata.
+func isReservedHeader(hdr string) bool {
+	switch strings.ToLower(hdr) {
+	case "content-type",
+		"user-agent",
+		"grpc-message-type",
+		"grpc-encoding",
+		"grpc-accept-encoding",
+		"grpc-message",
+		"grpc-status",
+		"grpc-timeout",
+		"grpc-status-details-bin",
+		"te",
+		"trailer",
+		"content-length",
+		"connection",
+		"accept-encoding",
+		"content-encoding",
+		"connect-protocol-version",
+		"connect-timeout-ms",
+		"connect-content-encoding",
+		"connect-accept-encoding":
+		return true
+	default:
+		return strings.HasPrefix(hdr, ":")
+	}
+}
+
+func encodeMetadataValue(k, v string) string {
+	if strings.HasSuffix(k, binHdrSuffix) {
+		return base64.RawStdEncoding.EncodeToString([]byte(v))
+	}
+
+	return v
+}
+
+func decodeMetadataValue(k, v string) (string, error) {
+	if !strings.HasSuffix(k, binHdrSuffix) {
+		return v, nil
+	}
+
+	// padding is optional
+	if len(v)%4 == 0 {
+		b, err := base64.StdEncoding.DecodeString(v)
+		return string(b), err
+	}
+
+	b, err := base64.RawStdEncoding.DecodeString(v)
+	return string(b), err
+}
+
+// encodeGrpcMessage percent-encodes a grpc-message value. Messages may contain any
+// text, but header values, and the lines of grpc-web trailers, may only contain
+// printable ASCII.
+func encodeGrpcMessage(msg string) string {
+	const hex = "0123456789ABCDEF"
+
+	var sb strings.Builder
+
+	for i := 0; i < len(msg); i++ {
+		c := msg[i]
+		if c >= ' ' && c <= '~' && c != '%' {
+			sb.WriteByte(c)
+			continue
+		}
+
+		sb.WriteByte('%')
+		sb.WriteByte(hex[c>>4])
+		sb.WriteByte(hex[c&0xf])
+	}
+
+	return sb.String()
+}
+
+// decodeGrpcMessage decodes a percent-encoded grpc-message value. Invalid escapes
+// are left as is.
+func decodeGrpcMessage(msg string) string {
+	if !strings.Contains(msg, "%") {
+		return msg
+	}
+
+	var sb strings.Builder
+
+	for i := 0; i < len(msg); i++ {
+		if msg[i] == '%' && i+2 < len(msg) {
+			if b, err := strconv.ParseUint(msg[i+1:i+3], 16, 8); err == nil {
+				sb.WriteByte(byte(b))
+				i += 2
+
+				continue
+			}
+		}
+
+		sb.WriteByte(msg[i])
+	}
+
+	return sb.String()
+}
+
+// setMetadataHeader adds md to header, skipping reserved headers.
+func setMetadataHeader(header http.Header, md metadata.MD) {
+	for k, vv := range md {
+		if isReservedHeader(k) {
+			continue
+		}
+
+		for _, v := range vv {
+			header.Add(k, encodeMetadataValue(k, v))
+		}
+	}
+}
+
+// metadataFromHeader creates metadata from header, skipping reserved headers.
+func metadataFromHeader(header http.Header) (metadata.MD, error) {
+	md := metadata.MD{}
+
+	for k, vv := range header {
+		k = strings.ToLower(k)
+		if isReservedHeader(k) {
+			continue
+		}
+
+		for _, v := range vv {
+			// binary values may be comma separated
+			if strings.HasSuffix(k, binHdrSuffix) {
+				for _, part := range strings.Split(v, ",") {
+					d, err := decodeMetadataValue(k, strings.TrimSpace(part))
+					if err != nil {
+						return nil, err
+					}
+					md[k] = append(md[k], d)
+				}
+				continue
+			}
+
+			md[k] = append(md[k], v)
+		}
+	}
+
+	return md, nil
+}
+
+// acceptEncodingHeader returns the sorted names of compressors, for use as grpc-accept-encoding.
+func acceptEncodingHeader(compressors map[string]Compressor) string {
+	names := make([]string, 0, len(compressors))
+	for name := range compressors {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return strings.Join(names, ",")
+}
+
+// acceptedEncodings parses an accept encoding header, such as grpc-accept-encoding.
+// It returns nil if the peer did not send it.
+func acceptedEncodings(header http.Header, name string) map[string]bool {
+	values := header.Values(name)
+	if len(values) == 0 {
+		return nil
+	}
+
+	accepted := make(map[string]bool)
+
+	for _, v := range values {
+		for _, encoding := range strings.Split(v, ",") {
+			// ignore any quality value, as in Accept-Encoding: gzip;q=1.0
+			if i := strings.IndexByte(encoding, ';'); i >= 0 {
+				encoding = encoding[:i]
+			}
+
+			if encoding = strings.TrimSpace(encoding); encoding != "" {
+				accepted[encoding] = true
+			}
+		}
+	}
+
+	return accepted
+}
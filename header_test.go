@@ -0,0 +1,40 @@
+package simplegrpc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGrpcMessageEncoding(t *testing.T) {
+	tests := []struct {
+		msg     string
+		encoded string
+	}{
+		{
+			msg:     "OK",
+			encoded: "OK",
+		},
+		{
+			msg:     "100% done",
+			encoded: "100%25 done",
+		},
+		{
+			msg:     "bad\r\ngrpc-status: 0",
+			encoded: "bad%0D%0Agrpc-status: 0",
+		},
+		{
+			msg:     "héllo",
+			encoded: "h%C3%A9llo",
+		},
+	}
+
+	for _, test := range tests {
+		require.Equal(t, test.encoded, encodeGrpcMessage(test.msg))
+		require.Equal(t, test.msg, decodeGrpcMessage(test.encoded))
+	}
+
+	// invalid escapes are not decoded
+	require.Equal(t, "100%", decodeGrpcMessage("100%"))
+	require.Equal(t, "%zz", decodeGrpcMessage("%zz"))
+}
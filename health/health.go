@@ -0,0 +1,177 @@
+// Package health implements the grpc.health.v1.Health service for a simplegrpc Handler,
+// as used by Kubernetes gRPC probes and load balancers.
+//
+// Services registered on the Handler report the overall status, which is the status of
+// the empty service name, unless their status is set with SetServingStatus. Other
+// services are unknown.
+package health
+
+import (
+	"context"
+	"sync"
+
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/bakins/simplegrpc"
+	"github.com/bakins/simplegrpc/codes"
+	"github.com/bakins/simplegrpc/status"
+)
+
+// Server implements the health service.
+type Server struct {
+	handler *simplegrpc.Handler
+
+	mu sync.Mutex
+	// shutdown is set once all services are NOT_SERVING, and status changes are ignored.
+	shutdown bool
+	statuses map[string]healthpb.HealthCheckResponse_ServingStatus
+	// watchers receive the status of the service they watch when any status changes.
+	watchers map[string]map[chan healthpb.HealthCheckResponse_ServingStatus]struct{}
+}
+
+// Register registers the health service on h. All services are SERVING until their
+// status is changed.
+func Register(h *simplegrpc.Handler) *Server {
+	s := &Server{
+		handler: h,
+		statuses: map[string]healthpb.HealthCheckResponse_ServingStatus{
+			"": healthpb.HealthCheckResponse_SERVING,
+		},
+		watchers: make(map[string]map[chan healthpb.HealthCheckResponse_ServingStatus]struct{}),
+	}
+
+	RegisterHealthSimpleServer(h, s)
+
+	return s
+}
+
+// SetServingStatus sets the status of a service. The empty service name sets the overall
+// status. It is ignored after Shutdown is called.
+func (s *Server) SetServingStatus(service string, servingStatus healthpb.HealthCheckResponse_ServingStatus) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.shutdown {
+		return
+	}
+
+	s.statuses[service] = servingStatus
+	s.notifyLocked()
+}
+
+// Shutdown sets the status of all services to NOT_SERVING, and ignores
+// further status changes until Resume is called.
+func (s *Server) Shutdown() {
+	s.setAll(true, healthpb.HealthCheckResponse_NOT_SERVING)
+}
+
+// Resume sets the status of all services to SERVING, and accepts status changes.
+func (s *Server) Resume() {
+	s.setAll(false, healthpb.HealthCheckResponse_SERVING)
+}
+
+func (s *Server) setAll(shutdown bool, servingStatus healthpb.HealthCheckResponse_ServingStatus) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.shutdown = shutdown
+	for service := range s.statuses {
+		s.statuses[service] = servingStatus
+	}
+
+	s.notifyLocked()
+}
+
+// statusLocked returns the status of a service, and whether the service is known.
+func (s *Server) statusLocked(service string) (healthpb.HealthCheckResponse_ServingStatus, bool) {
+	if servingStatus, ok := s.statuses[service]; ok {
+		return servingStatus, true
+	}
+
+	if _, ok := s.handler.GetServiceInfo()[service]; ok {
+		return s.statuses[""], true
+	}
+
+	return healthpb.HealthCheckResponse_SERVICE_UNKNOWN, false
+}
+
+// notifyLocked sends the current status to all watchers. A status that has not been
+// received yet is replaced, so slow watchers only see the latest status.
+func (s *Server) notifyLocked() {
+	for service, watchers := range s.watchers {
+		servingStatus, _ := s.statusLocked(service)
+
+		for update := range watchers {
+			select {
+			case <-update:
+			default:
+			}
+
+			update <- servingStatus
+		}
+	}
+}
+
+// Check returns the status of a service. It fails with NotFound for unknown services.
+func (s *Server) Check(ctx context.Context, in *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	servingStatus, ok := s.statusLocked(in.GetService())
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "unknown service %q", in.GetService())
+	}
+
+	return &healthpb.HealthCheckResponse{
+		Status: servingStatus,
+	}, nil
+}
+
+// Watch sends the status of a service, and then every change to it, until the client
+// cancels the call. Unknown services are SERVICE_UNKNOWN.
+func (s *Server) Watch(in *healthpb.HealthCheckRequest, stream Health_WatchSimpleServer) error {
+	service := in.GetService()
+	update := make(chan healthpb.HealthCheckResponse_ServingStatus, 1)
+
+	s.mu.Lock()
+
+	servingStatus, _ := s.statusLocked(service)
+	update <- servingStatus
+
+	if _, ok := s.watchers[service]; !ok {
+		s.watchers[service] = make(map[chan healthpb.HealthCheckResponse_ServingStatus]struct{})
+	}
+
+	s.watchers[service][update] = struct{}{}
+
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		delete(s.watchers[service], update)
+		if len(s.watchers[service]) == 0 {
+			delete(s.watchers, service)
+		}
+	}()
+
+	var lastSent healthpb.HealthCheckResponse_ServingStatus = -1
+
+	for {
+		select {
+		case servingStatus := <-update:
+			if servingStatus == lastSent {
+				continue
+			}
+
+			lastSent = servingStatus
+
+			if err := stream.Send(&healthpb.HealthCheckResponse{Status: servingStatus}); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return status.FromContextError(stream.Context().Err()).Err()
+		}
+	}
+}
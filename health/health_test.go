@@ -0,0 +1,122 @@
+package health
+
+import (
+	"context"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/grpc"
+	grpccodes "google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	grpcstatus "google.golang.org/grpc/status"
+
+	"github.com/bakins/simplegrpc"
+)
+
+// healthService is the name of the health service, which is itself a registered service.
+const healthService = "grpc.health.v1.Health"
+
+func setup(t *testing.T) (*Server, healthpb.HealthClient) {
+	h := simplegrpc.NewHandler()
+	s := Register(h)
+
+	svr := httptest.NewServer(h2c.NewHandler(h, &http2.Server{}))
+	t.Cleanup(svr.Close)
+
+	u, err := url.Parse(svr.URL)
+	require.NoError(t, err)
+
+	conn, err := grpc.Dial(u.Host, grpc.WithInsecure())
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return s, healthpb.NewHealthClient(conn)
+}
+
+func TestCheck(t *testing.T) {
+	s, client := setup(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	check := func(service string) (healthpb.HealthCheckResponse_ServingStatus, error) {
+		resp, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+		return resp.GetStatus(), err
+	}
+
+	servingStatus, err := check("")
+	require.NoError(t, err)
+	require.Equal(t, healthpb.HealthCheckResponse_SERVING, servingStatus)
+
+	servingStatus, err = check(healthService)
+	require.NoError(t, err)
+	require.Equal(t, healthpb.HealthCheckResponse_SERVING, servingStatus)
+
+	_, err = check("unknown.Service")
+	require.Equal(t, grpccodes.NotFound, grpcstatus.Code(err))
+
+	s.SetServingStatus(healthService, healthpb.HealthCheckResponse_NOT_SERVING)
+
+	servingStatus, err = check(healthService)
+	require.NoError(t, err)
+	require.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, servingStatus)
+
+	// services that are not registered are known once their status is set
+	s.SetServingStatus("unknown.Service", healthpb.HealthCheckResponse_SERVING)
+
+	servingStatus, err = check("unknown.Service")
+	require.NoError(t, err)
+	require.Equal(t, healthpb.HealthCheckResponse_SERVING, servingStatus)
+
+	s.Shutdown()
+	s.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+
+	servingStatus, err = check("")
+	require.NoError(t, err)
+	require.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, servingStatus)
+
+	s.Resume()
+
+	servingStatus, err = check(healthService)
+	require.NoError(t, err)
+	require.Equal(t, healthpb.HealthCheckResponse_SERVING, servingStatus)
+}
+
+func TestWatch(t *testing.T) {
+	s, client := setup(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	stream, err := client.Watch(ctx, &healthpb.HealthCheckRequest{Service: healthService})
+	require.NoError(t, err)
+
+	resp, err := stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.GetStatus())
+
+	// the service follows the overall status
+	s.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+
+	resp, err = stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, resp.GetStatus())
+
+	s.SetServingStatus(healthService, healthpb.HealthCheckResponse_SERVING)
+
+	resp, err = stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.GetStatus())
+
+	unknown, err := client.Watch(ctx, &healthpb.HealthCheckRequest{Service: "unknown.Service"})
+	require.NoError(t, err)
+
+	resp, err = unknown.Recv()
+	require.NoError(t, err)
+	require.Equal(t, healthpb.HealthCheckResponse_SERVICE_UNKNOWN, resp.GetStatus())
+}
@@ -0,0 +1,143 @@
+package simplegrpc
+
+import (
+	"context"
+)
+
+// UnaryServerInfo consists of various information about a unary RPC on
+// server side. All per-rpc information may be mutated by the interceptor.
+type UnaryServerInfo struct {
+	// Server is the service implementation the user provides. This is read-only.
+	Server interface{}
+	// FullMethod is the full RPC method string, i.e., /package.service/method.
+	FullMethod string
+}
+
+// UnaryHandler defines the handler invoked by UnaryServerInterceptor to complete the normal
+// execution of a unary RPC. If a UnaryHandler returns an error, it should be produced by the
+// status package, or else gRPC will use codes.Unknown as the status code and err.Error() as
+// the status message of the RPC.
+type UnaryHandler func(ctx context.Context, req interface{}) (interface{}, error)
+
+// UnaryServerInterceptor provides a hook to intercept the execution of a unary RPC on the server. info
+// contains all the information of this RPC the interceptor can operate on. And handler is the wrapper
+// of the service method implementation. It is the responsibility of the interceptor to invoke handler
+// to complete the RPC.
+type UnaryServerInterceptor func(ctx context.Context, req interface{}, info *UnaryServerInfo, handler UnaryHandler) (resp interface{}, err error)
+
+// MethodHandler handles a unary RPC. dec decodes the request. If interceptor is not nil, it must
+// be used to call the service method implementation. It is called from the IDL generated code.
+type MethodHandler func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor UnaryServerInterceptor) (interface{}, error)
+
+// unaryStreamHandler adapts a unary method handler to a stream handler, so unary
+// methods are served and intercepted like any other method.
+func (h *Handler) unaryStreamHandler(handler MethodHandler) StreamHandler {
+	return func(srv interface{}, stream ServerStream) error {
+		out, err := handler(srv, stream.Context(), stream.RecvMsg, h.unaryInterceptor)
+		if err != nil {
+			return err
+		}
+
+		return stream.SendMsg(out)
+	}
+}
+
+// chainStreamServerInterceptors creates a single interceptor out of a chain of many interceptors.
+// Execution is done in left-to-right order.
+func chainStreamServerInterceptors(interceptors []StreamServerInterceptor) StreamServerInterceptor {
+	switch len(interceptors) {
+	case 0:
+		return nil
+	case 1:
+		return interceptors[0]
+	}
+
+	return func(srv interface{}, ss ServerStream, info *StreamServerInfo, handler StreamHandler) error {
+		return interceptors[0](srv, ss, info, chainStreamHandler(interceptors, 0, info, handler))
+	}
+}
+
+func chainStreamHandler(interceptors []StreamServerInterceptor, curr int, info *StreamServerInfo, finalHandler StreamHandler) StreamHandler {
+	if curr == len(interceptors)-1 {
+		return finalHandler
+	}
+
+	return func(srv interface{}, ss ServerStream) error {
+		return interceptors[curr+1](srv, ss, info, chainStreamHandler(interceptors, curr+1, info, finalHandler))
+	}
+}
+
+// chainUnaryServerInterceptors creates a single interceptor out of a chain of many interceptors.
+// Execution is done in left-to-right order.
+func chainUnaryServerInterceptors(interceptors []UnaryServerInterceptor) UnaryServerInterceptor {
+	switch len(interceptors) {
+	case 0:
+		return nil
+	case 1:
+		return interceptors[0]
+	}
+
+	return func(ctx context.Context, req interface{}, info *UnaryServerInfo, handler UnaryHandler) (interface{}, error) {
+		return interceptors[0](ctx, req, info, chainUnaryHandler(interceptors, 0, info, handler))
+	}
+}
+
+func chainUnaryHandler(interceptors []UnaryServerInterceptor, curr int, info *UnaryServerInfo, finalHandler UnaryHandler) UnaryHandler {
+	if curr == len(interceptors)-1 {
+		return finalHandler
+	}
+
+	return func(ctx context.Context, req interface{}) (interface{}, error) {
+		return interceptors[curr+1](ctx, req, info, chainUnaryHandler(interceptors, curr+1, info, finalHandler))
+	}
+}
+
+// chainStreamClientInterceptors creates a single interceptor out of a chain of many interceptors.
+// Execution is done in left-to-right order.
+func chainStreamClientInterceptors(interceptors []StreamClientInterceptor) StreamClientInterceptor {
+	switch len(interceptors) {
+	case 0:
+		return nil
+	case 1:
+		return interceptors[0]
+	}
+
+	return func(ctx context.Context, desc *StreamDesc, cc ClientConn, method string, streamer Streamer) (ClientStream, error) {
+		return interceptors[0](ctx, desc, cc, method, chainStreamer(interceptors, 0, streamer))
+	}
+}
+
+func chainStreamer(interceptors []StreamClientInterceptor, curr int, finalStreamer Streamer) Streamer {
+	if curr == len(interceptors)-1 {
+		return finalStreamer
+	}
+
+	return func(ctx context.Context, desc *StreamDesc, cc ClientConn, method string) (ClientStream, error) {
+		return interceptors[curr+1](ctx, desc, cc, method, chainStreamer(interceptors, curr+1, finalStreamer))
+	}
+}
+
+// chainUnaryClientInterceptors creates a single interceptor out of a chain of many interceptors.
+// Execution is done in left-to-right order.
+func chainUnaryClientInterceptors(interceptors []UnaryClientInterceptor) UnaryClientInterceptor {
+	switch len(interceptors) {
+	case 0:
+		return nil
+	case 1:
+		return interceptors[0]
+	}
+
+	return func(ctx context.Context, method string, req, reply interface{}, cc ClientConn, invoker UnaryInvoker, opts ...CallOption) error {
+		return interceptors[0](ctx, method, req, reply, cc, chainUnaryInvoker(interceptors, 0, invoker), opts...)
+	}
+}
+
+func chainUnaryInvoker(interceptors []UnaryClientInterceptor, curr int, finalInvoker UnaryInvoker) UnaryInvoker {
+	if curr == len(interceptors)-1 {
+		return finalInvoker
+	}
+
+	return func(ctx context.Context, method string, req, reply interface{}, cc ClientConn, opts ...CallOption) error {
+		return interceptors[curr+1](ctx, method, req, reply, cc, chainUnaryInvoker(interceptors, curr+1, finalInvoker), opts...)
+	}
+}
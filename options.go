@@ -0,0 +1,65 @@
+package simplegrpc
+
+import "math"
+
+const (
+	defaultMaxRecvMsgSize = 1024 * 1024 * 4
+	defaultMaxSendMsgSize = math.MaxInt32
+)
+
+// SharedOption configures both a Handler and a ClientConn. It may be passed
+// to either NewHandler or NewClientConn.
+type SharedOption interface {
+	Option
+	HandlerOption
+}
+
+type sharedOptions struct {
+	maxRecvMsgSize       int
+	maxSendMsgSize       int
+	compressionThreshold int
+}
+
+func defaultSharedOptions() sharedOptions {
+	return sharedOptions{
+		maxRecvMsgSize: defaultMaxRecvMsgSize,
+		maxSendMsgSize: defaultMaxSendMsgSize,
+	}
+}
+
+type sharedOptionFunc func(*sharedOptions)
+
+func (f sharedOptionFunc) apply(o *Options) {
+	f(&o.shared)
+}
+
+func (f sharedOptionFunc) applyHandler(o *handlerOptions) {
+	f(&o.shared)
+}
+
+// WithMaxRecvMsgSize sets the maximum size in bytes of a message that can be received.
+// The limit applies to the message after decompression. Default is 4MB.
+// Larger messages fail with codes.ResourceExhausted.
+func WithMaxRecvMsgSize(n int) SharedOption {
+	return sharedOptionFunc(func(o *sharedOptions) {
+		o.maxRecvMsgSize = n
+	})
+}
+
+// WithMaxSendMsgSize sets the maximum size in bytes of a message that can be sent.
+// The limit applies to the message after compression. Default is math.MaxInt32.
+// Larger messages fail with codes.ResourceExhausted.
+func WithMaxSendMsgSize(n int) SharedOption {
+	return sharedOptionFunc(func(o *sharedOptions) {
+		o.maxSendMsgSize = n
+	})
+}
+
+// WithCompressionThreshold sets the size in bytes below which messages are sent
+// uncompressed, even if a compressor is in use. Default is 0, which compresses
+// all messages.
+func WithCompressionThreshold(n int) SharedOption {
+	return sharedOptionFunc(func(o *sharedOptions) {
+		o.compressionThreshold = n
+	})
+}
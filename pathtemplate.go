@@ -0,0 +1,201 @@
+package simplegrpc
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// pathTemplate is a parsed google.api.http path template:
+//
+//	Template = "/" Segments [ Verb ] ;
+//	Segments = Segment { "/" Segment } ;
+//	Segment  = "*" | "**" | LITERAL | Variable ;
+//	Variable = "{" FieldPath [ "=" Segments ] "}" ;
+//	Verb     = ":" LITERAL ;
+type pathTemplate struct {
+	segments []templateSegment
+	// variables refer to ranges of segments.
+	variables []templateVariable
+	verb      string
+}
+
+type templateSegment struct {
+	// literal is the segment to match, or * or ** for wildcards.
+	literal string
+}
+
+type templateVariable struct {
+	fieldPath string
+	// start and end are the indexes of the first and last segment of the variable, exclusive.
+	start, end int
+}
+
+func parsePathTemplate(template string) (*pathTemplate, error) {
+	if !strings.HasPrefix(template, "/") {
+		return nil, fmt.Errorf("path template %q must start with /", template)
+	}
+
+	t := pathTemplate{}
+
+	rest := template[1:]
+
+	// the verb follows the last segment, outside of any variable.
+	if i := strings.LastIndexByte(rest, ':'); i >= 0 && !strings.ContainsAny(rest[i:], "/}") {
+		t.verb = rest[i+1:]
+		rest = rest[:i]
+
+		if t.verb == "" {
+			return nil, fmt.Errorf("path template %q has an empty verb", template)
+		}
+	}
+
+	for len(rest) > 0 {
+		if rest[0] == '{' {
+			end := strings.IndexByte(rest, '}')
+			if end < 0 {
+				return nil, fmt.Errorf("path template %q has an unterminated variable", template)
+			}
+
+			if err := t.addVariable(rest[1:end]); err != nil {
+				return nil, fmt.Errorf("path template %q: %w", template, err)
+			}
+
+			rest = rest[end+1:]
+		} else {
+			end := strings.IndexByte(rest, '/')
+			if end < 0 {
+				end = len(rest)
+			}
+
+			if err := t.addSegments(rest[:end]); err != nil {
+				return nil, fmt.Errorf("path template %q: %w", template, err)
+			}
+
+			rest = rest[end:]
+		}
+
+		if rest == "" {
+			break
+		}
+
+		if rest[0] != '/' || len(rest) == 1 {
+			return nil, fmt.Errorf("path template %q has an invalid segment", template)
+		}
+
+		rest = rest[1:]
+	}
+
+	// ** matches the rest of the path, so it must be last.
+	for i, s := range t.segments {
+		if s.literal == "**" && i != len(t.segments)-1 {
+			return nil, fmt.Errorf("path template %q has ** before the last segment", template)
+		}
+	}
+
+	return &t, nil
+}
+
+func (t *pathTemplate) addVariable(v string) error {
+	fieldPath, segments := v, "*"
+	if i := strings.IndexByte(v, '='); i >= 0 {
+		fieldPath, segments = v[:i], v[i+1:]
+	}
+
+	if fieldPath == "" {
+		return errors.New("variable has no field path")
+	}
+
+	start := len(t.segments)
+	if err := t.addSegments(segments); err != nil {
+		return err
+	}
+
+	t.variables = append(t.variables, templateVariable{
+		fieldPath: fieldPath,
+		start:     start,
+		end:       len(t.segments),
+	})
+
+	return nil
+}
+
+func (t *pathTemplate) addSegments(segments string) error {
+	for _, s := range strings.Split(segments, "/") {
+		if s == "" || strings.ContainsAny(s, "{}=") {
+			return fmt.Errorf("invalid segment %q", s)
+		}
+
+		t.segments = append(t.segments, templateSegment{literal: s})
+	}
+
+	return nil
+}
+
+// match matches an escaped request path. It returns the values of the variables, by field path.
+func (t *pathTemplate) match(path string) (map[string]string, bool) {
+	if !strings.HasPrefix(path, "/") {
+		return nil, false
+	}
+
+	path = path[1:]
+
+	if t.verb != "" {
+		if !strings.HasSuffix(path, ":"+t.verb) {
+			return nil, false
+		}
+
+		path = path[:len(path)-len(t.verb)-1]
+	}
+
+	parts := strings.Split(path, "/")
+
+	deep := len(t.segments) > 0 && t.segments[len(t.segments)-1].literal == "**"
+
+	switch {
+	case deep && len(parts) < len(t.segments)-1:
+		return nil, false
+	case !deep && len(parts) != len(t.segments):
+		return nil, false
+	}
+
+	for i, s := range t.segments {
+		switch s.literal {
+		case "**":
+			// matches the rest of the path
+		case "*":
+			if i >= len(parts) || parts[i] == "" {
+				return nil, false
+			}
+		default:
+			if parts[i] != s.literal {
+				return nil, false
+			}
+		}
+	}
+
+	vars := make(map[string]string, len(t.variables))
+
+	for _, v := range t.variables {
+		end := v.end
+		if end == len(t.segments) {
+			// the last variable includes any segments matched by **
+			end = len(parts)
+		}
+
+		var values []string
+		for _, part := range parts[v.start:end] {
+			value, err := url.PathUnescape(part)
+			if err != nil {
+				return nil, false
+			}
+
+			values = append(values, value)
+		}
+
+		vars[v.fieldPath] = strings.Join(values, "/")
+	}
+
+	return vars, true
+}
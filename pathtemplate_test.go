@@ -0,0 +1,103 @@
+package simplegrpc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePathTemplate(t *testing.T) {
+	tests := []struct {
+		template string
+		err      bool
+	}{
+		{template: "/v1/features"},
+		{template: "/v1/{name=shelves/*/books/*}"},
+		{template: "/v1/{name=**}:publish"},
+		{template: "v1/features", err: true},
+		{template: "/v1/{name", err: true},
+		{template: "/v1//features", err: true},
+		{template: "/v1/features/", err: true},
+		{template: "/v1/**/features", err: true},
+		{template: "/v1/features:", err: true},
+		{template: "/v1/{=*}", err: true},
+	}
+
+	for _, test := range tests {
+		_, err := parsePathTemplate(test.template)
+		if test.err {
+			require.Error(t, err, test.template)
+		} else {
+			require.NoError(t, err, test.template)
+		}
+	}
+}
+
+func TestPathTemplateMatch(t *testing.T) {
+	tests := []struct {
+		template string
+		path     string
+		vars     map[string]string
+		noMatch  bool
+	}{
+		{
+			template: "/v1/features",
+			path:     "/v1/features",
+			vars:     map[string]string{},
+		},
+		{
+			template: "/v1/features/{latitude}/{longitude}",
+			path:     "/v1/features/1/-2",
+			vars:     map[string]string{"latitude": "1", "longitude": "-2"},
+		},
+		{
+			template: "/v1/{name=shelves/*/books/*}",
+			path:     "/v1/shelves/a%20b/books/c",
+			vars:     map[string]string{"name": "shelves/a b/books/c"},
+		},
+		{
+			template: "/v1/{name=files/**}",
+			path:     "/v1/files/a/b/c",
+			vars:     map[string]string{"name": "files/a/b/c"},
+		},
+		{
+			template: "/v1/{name=*}:publish",
+			path:     "/v1/books:publish",
+			vars:     map[string]string{"name": "books"},
+		},
+		{
+			template: "/v1/{name=*}:publish",
+			path:     "/v1/books",
+			noMatch:  true,
+		},
+		{
+			template: "/v1/features/{latitude}",
+			path:     "/v1/features/1/2",
+			noMatch:  true,
+		},
+		{
+			template: "/v1/features/{latitude}",
+			path:     "/v1/features/",
+			noMatch:  true,
+		},
+		{
+			template: "/v1/features",
+			path:     "/v2/features",
+			noMatch:  true,
+		},
+	}
+
+	for _, test := range tests {
+		template, err := parsePathTemplate(test.template)
+		require.NoError(t, err, test.template)
+
+		vars, ok := template.match(test.path)
+		if test.noMatch {
+			require.False(t, ok, test.path)
+			continue
+		}
+
+		require.True(t, ok, test.path)
+		require.Equal(t, test.vars, vars, test.path)
+	}
+}
@@ -0,0 +1,297 @@
+// Package reflection implements the gRPC server reflection service for a simplegrpc
+// Handler, so that tools such as grpcurl can discover its services.
+//
+// Both grpc.reflection.v1alpha and grpc.reflection.v1 are served, using the messages of
+// google.golang.org/grpc/reflection/grpc_reflection_v1alpha, as the versions are wire
+// compatible. The version of google.golang.org/grpc this module requires does not
+// provide v1 messages, so the v1 file descriptor is derived from the v1alpha one, and
+// is only known to this package, rather than registered where it would conflict with
+// the messages of later versions. Other file descriptors are resolved from the global
+// proto registry, using the Metadata file path of each registered ServiceDesc.
+package reflection
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+
+	"github.com/bakins/simplegrpc"
+	"github.com/bakins/simplegrpc/codes"
+	"github.com/bakins/simplegrpc/status"
+)
+
+// Register registers the server reflection services on h. Services registered on h
+// later are also listed.
+func Register(h *simplegrpc.Handler) {
+	s := &server{
+		handler: h,
+	}
+
+	RegisterServerReflectionSimpleServer(h, s)
+	h.RegisterService(&v1ServiceDesc, s)
+}
+
+const v1Path = "grpc/reflection/v1/reflection.proto"
+
+// v1ServiceDesc serves v1 with the v1alpha handler, as only the service name differs.
+var v1ServiceDesc = simplegrpc.ServiceDesc{
+	ServiceName: "grpc.reflection.v1.ServerReflection",
+	HandlerType: _ServerReflection_simple_serviceDesc.HandlerType,
+	Streams:     _ServerReflection_simple_serviceDesc.Streams,
+	Metadata:    v1Path,
+}
+
+// files holds the v1 file descriptor.
+var files = newV1Files()
+
+// newV1Files derives the v1 file descriptor from the v1alpha one, by renaming its
+// package.
+func newV1Files() *protoregistry.Files {
+	const (
+		v1alphaPrefix = ".grpc.reflection.v1alpha."
+		v1Prefix      = ".grpc.reflection.v1."
+	)
+
+	fdp := protodesc.ToFileDescriptorProto(rpb.File_reflection_grpc_reflection_v1alpha_reflection_proto)
+	fdp.Name = proto.String(v1Path)
+	fdp.Package = proto.String("grpc.reflection.v1")
+	fdp.Options = nil
+
+	for _, m := range fdp.MessageType {
+		for _, f := range m.Field {
+			if f.TypeName != nil {
+				f.TypeName = proto.String(strings.Replace(f.GetTypeName(), v1alphaPrefix, v1Prefix, 1))
+			}
+		}
+	}
+
+	for _, svc := range fdp.Service {
+		for _, m := range svc.Method {
+			m.InputType = proto.String(strings.Replace(m.GetInputType(), v1alphaPrefix, v1Prefix, 1))
+			m.OutputType = proto.String(strings.Replace(m.GetOutputType(), v1alphaPrefix, v1Prefix, 1))
+		}
+	}
+
+	fd, err := protodesc.NewFile(fdp, nil)
+	if err != nil {
+		panic(fmt.Sprintf("reflection: failed to derive the v1 file descriptor: %v", err))
+	}
+
+	files := new(protoregistry.Files)
+	if err := files.RegisterFile(fd); err != nil {
+		panic(fmt.Sprintf("reflection: failed to register the v1 file descriptor: %v", err))
+	}
+
+	return files
+}
+
+// findFileByPath finds a file in the v1 files, or the global registry.
+func findFileByPath(path string) (protoreflect.FileDescriptor, error) {
+	if fd, err := files.FindFileByPath(path); err == nil {
+		return fd, nil
+	}
+
+	return protoregistry.GlobalFiles.FindFileByPath(path)
+}
+
+// findDescriptorByName finds a descriptor in the v1 files, or the global registry.
+func findDescriptorByName(name protoreflect.FullName) (protoreflect.Descriptor, error) {
+	if d, err := files.FindDescriptorByName(name); err == nil {
+		return d, nil
+	}
+
+	return protoregistry.GlobalFiles.FindDescriptorByName(name)
+}
+
+type server struct {
+	handler *simplegrpc.Handler
+}
+
+func (s *server) ServerReflectionInfo(stream ServerReflection_ServerReflectionInfoSimpleServer) error {
+	// files are only sent once per stream, unless they are requested by name.
+	sent := make(map[string]bool)
+
+	for {
+		in, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		out := rpb.ServerReflectionResponse{
+			ValidHost:       in.Host,
+			OriginalRequest: in,
+		}
+
+		switch req := in.MessageRequest.(type) {
+		case *rpb.ServerReflectionRequest_FileByFilename:
+			fd, err := findFileByPath(req.FileByFilename)
+			setFileDescriptorResponse(&out, fd, err, sent)
+		case *rpb.ServerReflectionRequest_FileContainingSymbol:
+			fd, err := s.fileContainingSymbol(req.FileContainingSymbol)
+			setFileDescriptorResponse(&out, fd, err, sent)
+		case *rpb.ServerReflectionRequest_FileContainingExtension:
+			fd, err := fileContainingExtension(req.FileContainingExtension)
+			setFileDescriptorResponse(&out, fd, err, sent)
+		case *rpb.ServerReflectionRequest_AllExtensionNumbersOfType:
+			setAllExtensionNumbers(&out, req.AllExtensionNumbersOfType)
+		case *rpb.ServerReflectionRequest_ListServices:
+			s.setListServices(&out)
+		default:
+			return status.Errorf(codes.InvalidArgument, "invalid MessageRequest: %v", in.MessageRequest)
+		}
+
+		if err := stream.Send(&out); err != nil {
+			return err
+		}
+	}
+}
+
+// fileContainingSymbol finds the file of a service or method using the metadata of
+// the registered service, and any other symbol using the global registry.
+func (s *server) fileContainingSymbol(name string) (protoreflect.FileDescriptor, error) {
+	for service, info := range s.handler.GetServiceInfo() {
+		if name != service && !strings.HasPrefix(name, service+".") {
+			continue
+		}
+
+		if path, ok := info.Metadata.(string); ok {
+			if fd, err := findFileByPath(path); err == nil {
+				return fd, nil
+			}
+		}
+	}
+
+	d, err := findDescriptorByName(protoreflect.FullName(name))
+	if err != nil {
+		return nil, err
+	}
+
+	return d.ParentFile(), nil
+}
+
+func fileContainingExtension(req *rpb.ExtensionRequest) (protoreflect.FileDescriptor, error) {
+	xt, err := protoregistry.GlobalTypes.FindExtensionByNumber(protoreflect.FullName(req.GetContainingType()), protoreflect.FieldNumber(req.GetExtensionNumber()))
+	if err != nil {
+		return nil, err
+	}
+
+	return xt.TypeDescriptor().ParentFile(), nil
+}
+
+func setAllExtensionNumbers(out *rpb.ServerReflectionResponse, name string) {
+	d, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(name))
+	if err != nil {
+		setErrorResponse(out, err)
+		return
+	}
+
+	if _, ok := d.(protoreflect.MessageDescriptor); !ok {
+		setErrorResponse(out, status.Errorf(codes.NotFound, "%q is not a message", name))
+		return
+	}
+
+	var numbers []int32
+	protoregistry.GlobalTypes.RangeExtensionsByMessage(protoreflect.FullName(name), func(xt protoreflect.ExtensionType) bool {
+		numbers = append(numbers, int32(xt.TypeDescriptor().Number()))
+		return true
+	})
+
+	sort.Slice(numbers, func(i, j int) bool {
+		return numbers[i] < numbers[j]
+	})
+
+	out.MessageResponse = &rpb.ServerReflectionResponse_AllExtensionNumbersResponse{
+		AllExtensionNumbersResponse: &rpb.ExtensionNumberResponse{
+			BaseTypeName:    name,
+			ExtensionNumber: numbers,
+		},
+	}
+}
+
+func (s *server) setListServices(out *rpb.ServerReflectionResponse) {
+	info := s.handler.GetServiceInfo()
+
+	names := make([]string, 0, len(info))
+	for name := range info {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	services := make([]*rpb.ServiceResponse, 0, len(names))
+	for _, name := range names {
+		services = append(services, &rpb.ServiceResponse{Name: name})
+	}
+
+	out.MessageResponse = &rpb.ServerReflectionResponse_ListServicesResponse{
+		ListServicesResponse: &rpb.ListServiceResponse{
+			Service: services,
+		},
+	}
+}
+
+// setFileDescriptorResponse responds with fd, and the files it imports that have not been sent yet.
+func setFileDescriptorResponse(out *rpb.ServerReflectionResponse, fd protoreflect.FileDescriptor, err error, sent map[string]bool) {
+	if err != nil {
+		setErrorResponse(out, err)
+		return
+	}
+
+	var files [][]byte
+
+	queue := []protoreflect.FileDescriptor{fd}
+	for len(queue) > 0 {
+		f := queue[0]
+		queue = queue[1:]
+
+		if f != fd && sent[f.Path()] {
+			continue
+		}
+
+		sent[f.Path()] = true
+
+		data, err := proto.Marshal(protodesc.ToFileDescriptorProto(f))
+		if err != nil {
+			setErrorResponse(out, err)
+			return
+		}
+
+		files = append(files, data)
+
+		imports := f.Imports()
+		for i := 0; i < imports.Len(); i++ {
+			queue = append(queue, imports.Get(i).FileDescriptor)
+		}
+	}
+
+	out.MessageResponse = &rpb.ServerReflectionResponse_FileDescriptorResponse{
+		FileDescriptorResponse: &rpb.FileDescriptorResponse{
+			FileDescriptorProto: files,
+		},
+	}
+}
+
+func setErrorResponse(out *rpb.ServerReflectionResponse, err error) {
+	code := codes.NotFound
+	if st, ok := status.FromError(err); ok {
+		code = st.Code()
+	}
+
+	out.MessageResponse = &rpb.ServerReflectionResponse_ErrorResponse{
+		ErrorResponse: &rpb.ErrorResponse{
+			ErrorCode:    int32(code),
+			ErrorMessage: err.Error(),
+		},
+	}
+}
@@ -0,0 +1,156 @@
+package reflection
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	// the google.api.http extension is registered for the extension requests
+	_ "google.golang.org/genproto/googleapis/api/annotations"
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"github.com/bakins/simplegrpc"
+	"github.com/bakins/simplegrpc/codes"
+)
+
+func setup(t *testing.T) string {
+	h := simplegrpc.NewHandler()
+	Register(h)
+
+	svr := httptest.NewServer(h2c.NewHandler(h, &http2.Server{}))
+	t.Cleanup(svr.Close)
+
+	return svr.URL
+}
+
+func fileNames(t *testing.T, resp *rpb.ServerReflectionResponse) []string {
+	var names []string
+
+	for _, data := range resp.GetFileDescriptorResponse().GetFileDescriptorProto() {
+		var fd descriptorpb.FileDescriptorProto
+		require.NoError(t, proto.Unmarshal(data, &fd))
+
+		names = append(names, fd.GetName())
+	}
+
+	return names
+}
+
+func TestServerReflectionInfo(t *testing.T) {
+	conn, err := simplegrpc.NewClientConn(setup(t))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	stream, err := NewServerReflectionSimpleClient(conn).ServerReflectionInfo(ctx)
+	require.NoError(t, err)
+
+	call := func(req *rpb.ServerReflectionRequest) *rpb.ServerReflectionResponse {
+		require.NoError(t, stream.Send(req))
+
+		resp, err := stream.Recv()
+		require.NoError(t, err)
+		require.True(t, proto.Equal(req, resp.GetOriginalRequest()))
+
+		return resp
+	}
+
+	resp := call(&rpb.ServerReflectionRequest{
+		MessageRequest: &rpb.ServerReflectionRequest_ListServices{},
+	})
+
+	var services []string
+	for _, s := range resp.GetListServicesResponse().GetService() {
+		services = append(services, s.GetName())
+	}
+
+	require.Equal(t, []string{
+		"grpc.reflection.v1.ServerReflection",
+		"grpc.reflection.v1alpha.ServerReflection",
+	}, services)
+
+	// the file of the reflection service is registered by its messages
+	resp = call(&rpb.ServerReflectionRequest{
+		MessageRequest: &rpb.ServerReflectionRequest_FileContainingSymbol{
+			FileContainingSymbol: "grpc.reflection.v1alpha.ServerReflection.ServerReflectionInfo",
+		},
+	})
+	require.Equal(t, []string{"reflection/grpc_reflection_v1alpha/reflection.proto"}, fileNames(t, resp))
+
+	resp = call(&rpb.ServerReflectionRequest{
+		MessageRequest: &rpb.ServerReflectionRequest_FileByFilename{
+			FileByFilename: "google/api/annotations.proto",
+		},
+	})
+	require.Equal(t, []string{
+		"google/api/annotations.proto",
+		"google/api/http.proto",
+		"google/protobuf/descriptor.proto",
+	}, fileNames(t, resp))
+
+	// dependencies are only sent once, but requested files are always sent
+
+	resp = call(&rpb.ServerReflectionRequest{
+		MessageRequest: &rpb.ServerReflectionRequest_FileContainingExtension{
+			FileContainingExtension: &rpb.ExtensionRequest{
+				ContainingType:  "google.protobuf.MethodOptions",
+				ExtensionNumber: 72295728,
+			},
+		},
+	})
+	require.Equal(t, []string{"google/api/annotations.proto"}, fileNames(t, resp))
+
+	resp = call(&rpb.ServerReflectionRequest{
+		MessageRequest: &rpb.ServerReflectionRequest_AllExtensionNumbersOfType{
+			AllExtensionNumbersOfType: "google.protobuf.MethodOptions",
+		},
+	})
+	require.Contains(t, resp.GetAllExtensionNumbersResponse().GetExtensionNumber(), int32(72295728))
+
+	resp = call(&rpb.ServerReflectionRequest{
+		MessageRequest: &rpb.ServerReflectionRequest_FileByFilename{
+			FileByFilename: "missing.proto",
+		},
+	})
+	require.Equal(t, int32(codes.NotFound), resp.GetErrorResponse().GetErrorCode())
+
+	require.NoError(t, stream.CloseSend())
+}
+
+func TestServerReflectionInfoV1(t *testing.T) {
+	conn, err := simplegrpc.NewClientConn(setup(t))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	// v1 has the same messages as v1alpha, so the v1alpha client is used with the v1 method.
+	stream, err := conn.NewStream(ctx, &_ServerReflection_simple_serviceDesc.Streams[0], "/grpc.reflection.v1.ServerReflection/ServerReflectionInfo")
+	require.NoError(t, err)
+
+	require.NoError(t, stream.SendMsg(&rpb.ServerReflectionRequest{
+		MessageRequest: &rpb.ServerReflectionRequest_FileContainingSymbol{
+			FileContainingSymbol: "grpc.reflection.v1.ServerReflection",
+		},
+	}))
+
+	var resp rpb.ServerReflectionResponse
+	require.NoError(t, stream.RecvMsg(&resp))
+	require.Equal(t, []string{"grpc/reflection/v1/reflection.proto"}, fileNames(t, &resp))
+
+	files := resp.GetFileDescriptorResponse().GetFileDescriptorProto()
+
+	var fd descriptorpb.FileDescriptorProto
+	require.NoError(t, proto.Unmarshal(files[0], &fd))
+	require.Equal(t, "grpc.reflection.v1", fd.GetPackage())
+	require.Equal(t, ".grpc.reflection.v1.ServerReflectionRequest", fd.GetService()[0].GetMethod()[0].GetInputType())
+
+	require.NoError(t, stream.CloseSend())
+}
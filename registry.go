@@ -0,0 +1,86 @@
+package simplegrpc
+
+import "sync"
+
+var (
+	registryMu           sync.RWMutex
+	registeredCodec      = map[string]Codec{ProtoCodec.Name(): ProtoCodec}
+	registeredCompressor = map[string]Compressor{}
+)
+
+// RegisterCodec registers a codec for use by every Handler and ClientConn created
+// afterwards, replacing any codec with the same name. It is intended to be called
+// from an init function. ProtoCodec is registered by default.
+func RegisterCodec(codec Codec) {
+	if codec == nil {
+		panic("cannot register a nil Codec")
+	}
+
+	if codec.Name() == "" {
+		panic("cannot register Codec with empty string result for Name()")
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	registeredCodec[codec.Name()] = codec
+}
+
+// GetCodec returns the registered codec with the given name, or nil if there is none.
+func GetCodec(name string) Codec {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	return registeredCodec[name]
+}
+
+// RegisterCompressor registers a compressor for use by every Handler and ClientConn
+// created afterwards, replacing any compressor with the same name. Handlers accept
+// requests compressed with it, and clients accept responses compressed with it.
+// It is intended to be called from an init function. No compressors are registered by default.
+func RegisterCompressor(compressor Compressor) {
+	if compressor == nil {
+		panic("cannot register a nil Compressor")
+	}
+
+	if compressor.Name() == "" {
+		panic("cannot register Compressor with empty string result for Name()")
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	registeredCompressor[compressor.Name()] = compressor
+}
+
+// GetCompressor returns the registered compressor with the given name, or nil if there is none.
+func GetCompressor(name string) Compressor {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	return registeredCompressor[name]
+}
+
+func registeredCodecs() map[string]Codec {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	out := make(map[string]Codec, len(registeredCodec))
+	for k, v := range registeredCodec {
+		out[k] = v
+	}
+
+	return out
+}
+
+func registeredCompressors() map[string]Compressor {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	out := make(map[string]Compressor, len(registeredCompressor))
+	for k, v := range registeredCompressor {
+		out[k] = v
+	}
+
+	return out
+}
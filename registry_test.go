@@ -0,0 +1,72 @@
+package simplegrpc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type namedCodec struct {
+	Codec
+	name string
+}
+
+func (n namedCodec) Name() string {
+	return n.name
+}
+
+type namedCompressor struct {
+	Compressor
+	name string
+}
+
+func (n namedCompressor) Name() string {
+	return n.name
+}
+
+// unregisterCodec removes a registered codec. It restores the registry after tests.
+func unregisterCodec(name string) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	delete(registeredCodec, name)
+}
+
+// unregisterCompressor removes a registered compressor. It restores the registry after tests.
+func unregisterCompressor(name string) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	delete(registeredCompressor, name)
+}
+
+func TestRegistry(t *testing.T) {
+	require.Equal(t, ProtoCodec, GetCodec("proto"))
+	require.Nil(t, GetCodec("registry-test"))
+	require.Nil(t, GetCompressor("registry-test"))
+
+	codec := namedCodec{Codec: ProtoCodec, name: "registry-test"}
+	RegisterCodec(codec)
+	t.Cleanup(func() { unregisterCodec("registry-test") })
+	require.Equal(t, codec, GetCodec("registry-test"))
+
+	compressor := namedCompressor{Compressor: GzipCompressor, name: "registry-test"}
+	RegisterCompressor(compressor)
+	t.Cleanup(func() { unregisterCompressor("registry-test") })
+	require.Equal(t, compressor, GetCompressor("registry-test"))
+
+	h := NewHandler()
+	require.Equal(t, codec, h.codecs["registry-test"])
+	require.Equal(t, compressor, h.compressors["registry-test"])
+
+	_, c, err := h.getCodec("application/grpc+registry-test")
+	require.NoError(t, err)
+	require.Equal(t, codec, c)
+
+	conn, err := NewClientConn("http://localhost")
+	require.NoError(t, err)
+	require.Contains(t, conn.(*clientConn).request.Header.Get("Grpc-Accept-Encoding"), "registry-test")
+
+	require.Panics(t, func() { RegisterCodec(nil) })
+	require.Panics(t, func() { RegisterCompressor(namedCompressor{Compressor: GzipCompressor}) })
+}
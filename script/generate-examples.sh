@@ -22,3 +22,34 @@ protoc \
     --go-simple-grpc_opt=paths=source_relative \
     --plugin=protoc-gen-go-simple-grpc=./script/gen.sh \
     ./examples/routeguide/routeguide/routeguide.proto
+
+# the reflection messages are those of google.golang.org/grpc/reflection/grpc_reflection_v1alpha,
+# so only the simple service is generated, into the reflection package.
+protoc \
+    --proto_path="$(go list -m -f '{{.Dir}}' google.golang.org/grpc)" \
+    --go-simple-grpc_out=. \
+    --go-simple-grpc_opt=module=github.com/bakins/simplegrpc \
+    --go-simple-grpc_opt="simple_package=github.com/bakins/simplegrpc/reflection;reflection" \
+    --plugin=protoc-gen-go-simple-grpc=./script/gen.sh \
+    reflection/grpc_reflection_v1alpha/reflection.proto
+
+# the health messages are those of google.golang.org/grpc/health/grpc_health_v1, so
+# only the simple service is generated, into the health package.
+protoc \
+    --proto_path=./third_party/grpc-proto \
+    --go-simple-grpc_out=. \
+    --go-simple-grpc_opt=module=github.com/bakins/simplegrpc \
+    --go-simple-grpc_opt="simple_package=github.com/bakins/simplegrpc/health;health" \
+    --plugin=protoc-gen-go-simple-grpc=./script/gen.sh \
+    grpc/health/v1/health.proto
+
+# the transcoding tests use a service with google.api.http annotations.
+protoc \
+    --proto_path=./testdata \
+    --proto_path=./third_party/googleapis \
+    --go_out=./testdata \
+    --go_opt=paths=source_relative \
+    --go-simple-grpc_out=./testdata \
+    --go-simple-grpc_opt=paths=source_relative \
+    --plugin=protoc-gen-go-simple-grpc=./script/gen.sh \
+    ./testdata/transcode/transcode.proto
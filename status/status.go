@@ -28,22 +28,31 @@
 package status
 
 import (
+	"context"
+	"errors"
 	"fmt"
 
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	grpcstatus "google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+
 	"github.com/bakins/simplegrpc/codes"
 )
 
-// represents an RPC status code and message.
+// Status represents an RPC status code, message, and details.  It is immutable
+// and should be created with New, Newf, or FromProto.
 type Status struct {
-	code    codes.Code
-	message string
+	s *spb.Status
 }
 
 // New returns a Status representing c and msg.
 func New(c codes.Code, msg string) *Status {
 	return &Status{
-		code:    c,
-		message: msg,
+		s: &spb.Status{
+			Code:    int32(c),
+			Message: msg,
+		},
 	}
 }
 
@@ -52,6 +61,13 @@ func Newf(c codes.Code, format string, a ...interface{}) *Status {
 	return New(c, fmt.Sprintf(format, a...))
 }
 
+// FromProto returns a Status representing s.
+func FromProto(s *spb.Status) *Status {
+	return &Status{
+		s: proto.Clone(s).(*spb.Status),
+	}
+}
+
 // Error returns an error representing c and msg.  If c is OK, returns nil.
 func Error(c codes.Code, msg string) error {
 	if c == codes.OK {
@@ -66,43 +82,172 @@ func Errorf(c codes.Code, format string, a ...interface{}) error {
 	return Error(c, fmt.Sprintf(format, a...))
 }
 
+// ErrorProto returns an error representing s.  If s.Code is OK, returns nil.
+func ErrorProto(s *spb.Status) error {
+	return FromProto(s).Err()
+}
+
 // Error Implements error interface
 func (s *Status) Error() string {
-	return fmt.Sprintf("rpc error: code = %s desc = %s", codes.Code(s.code), s.message)
+	return fmt.Sprintf("rpc error: code = %s desc = %s", s.Code(), s.Message())
+}
+
+// Err returns an immutable error representing s; returns nil if s.Code() is OK.
+func (s *Status) Err() error {
+	if s.Code() == codes.OK {
+		return nil
+	}
+
+	return s
 }
 
 // Code returns the status code contained in s.
 func (s *Status) Code() codes.Code {
-	if s == nil {
+	if s == nil || s.s == nil {
 		return codes.OK
 	}
-	return s.code
+	return codes.Code(s.s.Code)
 }
 
 // Message returns the message contained in s.
 func (s *Status) Message() string {
-	if s == nil {
+	if s == nil || s.s == nil {
 		return ""
 	}
-	return s.message
+	return s.s.Message
 }
 
-// GRPCStatus ...
-func (s *Status) GRPCStatus() *Status {
-	return s
+// Proto returns s's status as an spb.Status proto message.
+func (s *Status) Proto() *spb.Status {
+	if s == nil {
+		return nil
+	}
+	return proto.Clone(s.s).(*spb.Status)
+}
+
+// WithDetails returns a new status with the provided details messages appended to the status.
+// If any errors are encountered, it returns nil and the first error encountered.
+func (s *Status) WithDetails(details ...proto.Message) (*Status, error) {
+	if s.Code() == codes.OK {
+		return nil, errors.New("no error details for status with code OK")
+	}
+
+	p := s.Proto()
+	for _, detail := range details {
+		any, err := anypb.New(detail)
+		if err != nil {
+			return nil, err
+		}
+		p.Details = append(p.Details, any)
+	}
+
+	return &Status{s: p}, nil
+}
+
+// Details returns a slice of details messages attached to the status.
+// If a detail cannot be decoded, the error is returned in place of the detail.
+func (s *Status) Details() []interface{} {
+	if s == nil || s.s == nil {
+		return nil
+	}
+
+	details := make([]interface{}, 0, len(s.s.Details))
+	for _, any := range s.s.Details {
+		detail, err := any.UnmarshalNew()
+		if err != nil {
+			details = append(details, err)
+			continue
+		}
+		details = append(details, detail)
+	}
+
+	return details
+}
+
+// Is implements future error.Is functionality.
+// A Status is equivalent if the code, message, and details are the same.
+func (s *Status) Is(target error) bool {
+	tse, ok := target.(*Status)
+	if !ok {
+		return false
+	}
+
+	return proto.Equal(s.s, tse.s)
+}
+
+// GRPCStatus returns s as a google.golang.org/grpc/status Status, so that errors
+// from this package are understood by google.golang.org/grpc.
+func (s *Status) GRPCStatus() *grpcstatus.Status {
+	return grpcstatus.FromProto(s.Proto())
+}
+
+// FromGRPCStatus returns a Status representing a google.golang.org/grpc/status Status.
+func FromGRPCStatus(s *grpcstatus.Status) *Status {
+	if s == nil {
+		return nil
+	}
+
+	return FromProto(s.Proto())
 }
 
 // FromError returns a Status representing err if it was produced from this
-// package or has a method `GRPCStatus() *Status`. Otherwise, ok is false and a
-// Status is returned with codes.Unknown and the original error message.
+// package or google.golang.org/grpc/status, or has a method `GRPCStatus() *Status`
+// or `GRPCStatus() *grpcstatus.Status` in its chain of wrapped errors. Otherwise,
+// ok is false and a Status is returned with codes.Unknown and the original error
+// message.
 func FromError(err error) (s *Status, ok bool) {
 	if err == nil {
 		return nil, true
 	}
-	if se, ok := err.(interface {
+
+	var st *Status
+	if errors.As(err, &st) {
+		return st, true
+	}
+
+	// errors written for earlier versions of this package return a *Status.
+	var ss interface {
 		GRPCStatus() *Status
-	}); ok {
-		return se.GRPCStatus(), true
 	}
+	if errors.As(err, &ss) {
+		return ss.GRPCStatus(), true
+	}
+
+	var gs interface {
+		GRPCStatus() *grpcstatus.Status
+	}
+	if errors.As(err, &gs) {
+		return FromGRPCStatus(gs.GRPCStatus()), true
+	}
+
 	return New(codes.Unknown, err.Error()), false
 }
+
+// Convert is a convenience function which removes the need to handle the
+// boolean return value from FromError.
+func Convert(err error) *Status {
+	s, _ := FromError(err)
+	return s
+}
+
+// Code returns the Code of the error if it is a Status error, codes.OK if err
+// is nil, or codes.Unknown otherwise.
+func Code(err error) codes.Code {
+	return Convert(err).Code()
+}
+
+// FromContextError converts a context error into a Status.  It returns a
+// Status with codes.OK if err is nil, or a Status with codes.Unknown if err is
+// non-nil and not a context error.
+func FromContextError(err error) *Status {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, context.DeadlineExceeded):
+		return New(codes.DeadlineExceeded, err.Error())
+	case errors.Is(err, context.Canceled):
+		return New(codes.Canceled, err.Error())
+	default:
+		return New(codes.Unknown, err.Error())
+	}
+}
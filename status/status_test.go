@@ -0,0 +1,131 @@
+package status
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	grpccodes "google.golang.org/grpc/codes"
+	grpcstatus "google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/bakins/simplegrpc/codes"
+)
+
+// legacyError implements the GRPCStatus method of earlier versions of this package.
+type legacyError struct{}
+
+func (legacyError) Error() string {
+	return "legacy"
+}
+
+func (legacyError) GRPCStatus() *Status {
+	return New(codes.Aborted, "legacy")
+}
+
+func TestFromError(t *testing.T) {
+	detail := &errdetails.RetryInfo{}
+
+	withDetails, err := New(codes.Unavailable, "try again").WithDetails(detail)
+	require.NoError(t, err)
+
+	grpcWithDetails, err := grpcstatus.New(grpccodes.Unavailable, "try again").WithDetails(detail)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		err     error
+		ok      bool
+		code    codes.Code
+		message string
+		details int
+	}{
+		{
+			name: "nil",
+			ok:   true,
+			code: codes.OK,
+		},
+		{
+			name:    "status",
+			err:     Error(codes.NotFound, "not found"),
+			ok:      true,
+			code:    codes.NotFound,
+			message: "not found",
+		},
+		{
+			name:    "wrapped status",
+			err:     fmt.Errorf("wrapped: %w", withDetails),
+			ok:      true,
+			code:    codes.Unavailable,
+			message: "try again",
+			details: 1,
+		},
+		{
+			name:    "grpc status",
+			err:     grpcWithDetails.Err(),
+			ok:      true,
+			code:    codes.Unavailable,
+			message: "try again",
+			details: 1,
+		},
+		{
+			name:    "wrapped grpc status",
+			err:     fmt.Errorf("wrapped: %w", grpcstatus.Error(grpccodes.NotFound, "not found")),
+			ok:      true,
+			code:    codes.NotFound,
+			message: "not found",
+		},
+		{
+			name:    "legacy status",
+			err:     fmt.Errorf("wrapped: %w", legacyError{}),
+			ok:      true,
+			code:    codes.Aborted,
+			message: "legacy",
+		},
+		{
+			name:    "other",
+			err:     errors.New("other"),
+			ok:      false,
+			code:    codes.Unknown,
+			message: "other",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			st, ok := FromError(test.err)
+			require.Equal(t, test.ok, ok)
+			require.Equal(t, test.code, st.Code())
+			require.Equal(t, test.message, st.Message())
+			require.Len(t, st.Details(), test.details)
+		})
+	}
+}
+
+func TestGRPCStatus(t *testing.T) {
+	st, err := New(codes.InvalidArgument, "invalid").WithDetails(&errdetails.BadRequest{})
+	require.NoError(t, err)
+
+	gs, ok := grpcstatus.FromError(st)
+	require.True(t, ok)
+	require.Equal(t, grpccodes.InvalidArgument, gs.Code())
+	require.Equal(t, "invalid", gs.Message())
+	require.True(t, proto.Equal(st.Proto(), gs.Proto()))
+
+	require.True(t, proto.Equal(st.Proto(), FromGRPCStatus(gs).Proto()))
+}
+
+func TestIs(t *testing.T) {
+	err := fmt.Errorf("wrapped: %w", Error(codes.NotFound, "not found"))
+
+	require.True(t, errors.Is(err, Error(codes.NotFound, "not found")))
+	require.False(t, errors.Is(err, Error(codes.NotFound, "other")))
+	require.False(t, errors.Is(err, Error(codes.Internal, "not found")))
+
+	var st *Status
+	require.True(t, errors.As(err, &st))
+	require.Equal(t, codes.NotFound, st.Code())
+}
@@ -0,0 +1,61 @@
+package simplegrpc
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeTimeout(t *testing.T) {
+	tests := []struct {
+		in   time.Duration
+		want string
+	}{
+		{in: 0, want: "0n"},
+		{in: -time.Second, want: "0n"},
+		{in: 10 * time.Nanosecond, want: "10n"},
+		{in: 100 * time.Millisecond, want: "100000u"},
+		{in: 5 * time.Second, want: "5000000u"},
+		{in: time.Second + time.Nanosecond, want: "1000001u"},
+		{in: 30 * time.Minute, want: "1800000m"},
+		{in: 1000 * time.Hour, want: "3600000S"},
+	}
+
+	for _, test := range tests {
+		require.Equal(t, test.want, encodeTimeout(test.in), test.in.String())
+	}
+}
+
+func TestDecodeTimeout(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Duration
+		err  bool
+	}{
+		{in: "1H", want: time.Hour},
+		{in: "2M", want: 2 * time.Minute},
+		{in: "3S", want: 3 * time.Second},
+		{in: "4m", want: 4 * time.Millisecond},
+		{in: "5u", want: 5 * time.Microsecond},
+		{in: "6n", want: 6 * time.Nanosecond},
+		{in: "99999999H", want: time.Duration(math.MaxInt64)},
+		{in: "1", err: true},
+		{in: "1x", err: true},
+		{in: "S", err: true},
+		{in: "-1S", err: true},
+		{in: "123456789S", err: true},
+	}
+
+	for _, test := range tests {
+		got, err := decodeTimeout(test.in)
+		if test.err {
+			require.Error(t, err, test.in)
+			continue
+		}
+
+		require.NoError(t, err, test.in)
+		require.Equal(t, test.want, got, test.in)
+	}
+}
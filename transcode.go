@@ -0,0 +1,379 @@
+package simplegrpc
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/bakins/simplegrpc/codes"
+	"github.com/bakins/simplegrpc/status"
+)
+
+// HTTPRule maps a unary method to a REST endpoint, as declared by a google.api.http
+// annotation. Additional bindings are separate rules.
+type HTTPRule struct {
+	// Method is the HTTP method, such as GET, or the kind of a custom pattern.
+	Method string
+	// Path is the path template, such as /v1/{name=shelves/*}.
+	Path string
+	// Body is the request field that is read from the request body, or * for the
+	// whole request. The body is not read if it is empty.
+	Body string
+	// ResponseBody is the response field that is written as the response body.
+	// The whole response is written if it is empty.
+	ResponseBody string
+}
+
+// httpRoute is a rule of a registered method.
+type httpRoute struct {
+	rule     HTTPRule
+	template *pathTemplate
+	method   *method
+}
+
+// httpBinding is a request that matched a route.
+type httpBinding struct {
+	route *httpRoute
+	// vars are the values of the path variables, by field path.
+	vars  map[string]string
+	query url.Values
+}
+
+// addHTTPRoute adds a route for a rule of a registered method.
+func (h *Handler) addHTTPRoute(rule HTTPRule, m *method) {
+	if m.streamDesc.ClientStreams || m.streamDesc.ServerStreams {
+		panic(fmt.Sprintf("grpc: RegisterService found HTTP rule for streaming method %q", m.streamDesc.StreamName))
+	}
+
+	template, err := parsePathTemplate(rule.Path)
+	if err != nil {
+		panic(fmt.Sprintf("grpc: RegisterService found invalid HTTP rule for %q: %v", m.streamDesc.StreamName, err))
+	}
+
+	h.httpRoutes = append(h.httpRoutes, &httpRoute{
+		rule:     rule,
+		template: template,
+		method:   m,
+	})
+}
+
+// matchHTTPRoute returns the binding of the first route that matches the request.
+// It returns nil if there is none, or the request is for a method path.
+func (h *Handler) matchHTTPRoute(r *http.Request) *httpBinding {
+	if len(h.httpRoutes) == 0 {
+		return nil
+	}
+
+	if _, ok := h.methodHandlers[r.URL.Path]; ok {
+		return nil
+	}
+
+	path := r.URL.EscapedPath()
+
+	for _, route := range h.httpRoutes {
+		if route.rule.Method != r.Method && route.rule.Method != "*" {
+			continue
+		}
+
+		if vars, ok := route.template.match(path); ok {
+			return &httpBinding{
+				route: route,
+				vars:  vars,
+				query: r.URL.Query(),
+			}
+		}
+	}
+
+	return nil
+}
+
+// jsonCodec returns the codec used for transcoded requests.
+func (h *Handler) jsonCodec() Codec {
+	if codec, ok := h.codecs[JSONCodec.Name()]; ok && codec != nil {
+		return codec
+	}
+
+	return JSONCodec
+}
+
+// recvHTTP reads the request of a transcoded request.
+func (s *serverStream) recvHTTP(m interface{}) error {
+	return s.recvUnary(func(body []byte) error {
+		return s.binding.decode(s.reader.codec, body, m)
+	})
+}
+
+// sendHTTP holds the response of a transcoded request until the status is known.
+func (s *serverStream) sendHTTP(m interface{}) error {
+	data, err := s.binding.encode(s.frameWriter.codec, m)
+	if err != nil {
+		return err
+	}
+
+	return s.setUnaryResponse(data)
+}
+
+// decode populates m from the request body, the path variables and the query parameters,
+// in that order.
+func (b *httpBinding) decode(codec Codec, body []byte, m interface{}) error {
+	msg, ok := messageV2Of(m)
+	if !ok {
+		return status.Errorf(codes.Internal, "failed to unmarshal, message is %T, want proto.Message", m)
+	}
+
+	if err := b.decodeBody(codec, body, msg); err != nil {
+		return err
+	}
+
+	for fieldPath, v := range b.vars {
+		if err := setField(msg.ProtoReflect(), fieldPath, []string{v}); err != nil {
+			return err
+		}
+	}
+
+	if b.route.rule.Body == "*" {
+		return nil
+	}
+
+	for name, values := range b.query {
+		if _, ok := b.vars[name]; ok || name == b.route.rule.Body {
+			continue
+		}
+
+		// unknown parameters, such as cache busters, are ignored
+		if err := setField(msg.ProtoReflect(), name, values); err != nil && status.Code(err) != codes.NotFound {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (b *httpBinding) decodeBody(codec Codec, body []byte, msg proto.Message) error {
+	field := b.route.rule.Body
+
+	if field == "" || len(body) == 0 {
+		return nil
+	}
+
+	if field == "*" {
+		if err := unmarshal(codec, body, msg); err != nil {
+			return status.Errorf(codes.InvalidArgument, "invalid request body: %v", err)
+		}
+
+		return nil
+	}
+
+	fd := msg.ProtoReflect().Descriptor().Fields().ByName(protoreflect.Name(field))
+	if fd == nil {
+		return status.Errorf(codes.Internal, "body field %q not found in %s", field, msg.ProtoReflect().Descriptor().FullName())
+	}
+
+	// the body is the JSON value of the field, so decode it as a message that only has the field.
+	wrapped := make([]byte, 0, len(body)+len(field)+5)
+	wrapped = append(wrapped, `{"`...)
+	wrapped = append(wrapped, field...)
+	wrapped = append(wrapped, `":`...)
+	wrapped = append(wrapped, body...)
+	wrapped = append(wrapped, '}')
+
+	tmp := msg.ProtoReflect().New()
+	if err := codec.Unmarshal(wrapped, tmp.Interface()); err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid request body: %v", err)
+	}
+
+	msg.ProtoReflect().Set(fd, tmp.Get(fd))
+
+	return nil
+}
+
+// encode marshals the response body.
+func (b *httpBinding) encode(codec Codec, m interface{}) ([]byte, error) {
+	field := b.route.rule.ResponseBody
+	if field == "" {
+		return codec.Marshal(m)
+	}
+
+	msg, ok := messageV2Of(m)
+	if !ok {
+		return nil, status.Errorf(codes.Internal, "failed to marshal, message is %T, want proto.Message", m)
+	}
+
+	fd := msg.ProtoReflect().Descriptor().Fields().ByName(protoreflect.Name(field))
+	if fd == nil {
+		return nil, status.Errorf(codes.Internal, "response body field %q not found in %s", field, msg.ProtoReflect().Descriptor().FullName())
+	}
+
+	if fd.Kind() == protoreflect.MessageKind && fd.Cardinality() != protoreflect.Repeated {
+		return codec.Marshal(msg.ProtoReflect().Get(fd).Message().Interface())
+	}
+
+	// other fields can only be marshaled as part of a message, so take the field's value
+	// from the marshaled response.
+	data, err := codec.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to marshal response body: %v", err)
+	}
+
+	if v, ok := fields[fd.JSONName()]; ok {
+		return v, nil
+	}
+
+	if v, ok := fields[string(fd.Name())]; ok {
+		return v, nil
+	}
+
+	// the codec omitted the field as it has its default value
+	data, err = protojson.MarshalOptions{EmitUnpopulated: true, UseProtoNames: true}.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	fields = nil
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to marshal response body: %v", err)
+	}
+
+	return fields[string(fd.Name())], nil
+}
+
+// setField sets the field at a dot separated path of proto or JSON field names. It
+// returns a NotFound error if there is no such field.
+func setField(msg protoreflect.Message, fieldPath string, values []string) error {
+	names := strings.Split(fieldPath, ".")
+
+	for i, name := range names {
+		fields := msg.Descriptor().Fields()
+
+		fd := fields.ByName(protoreflect.Name(name))
+		if fd == nil {
+			fd = fields.ByJSONName(name)
+		}
+
+		if fd == nil {
+			return status.Errorf(codes.NotFound, "field %q not found in %s", fieldPath, msg.Descriptor().FullName())
+		}
+
+		if i < len(names)-1 {
+			if fd.Kind() != protoreflect.MessageKind || fd.Cardinality() == protoreflect.Repeated {
+				return status.Errorf(codes.InvalidArgument, "field %q is not a message in %q", name, fieldPath)
+			}
+
+			msg = msg.Mutable(fd).Message()
+
+			continue
+		}
+
+		if fd.IsMap() {
+			return status.Errorf(codes.InvalidArgument, "map field %q can not be set from a parameter", fieldPath)
+		}
+
+		if fd.Cardinality() != protoreflect.Repeated {
+			if len(values) != 1 {
+				return status.Errorf(codes.InvalidArgument, "field %q is not repeated", fieldPath)
+			}
+
+			v, err := parseFieldValue(fd, values[0], msg.NewField(fd))
+			if err != nil {
+				return err
+			}
+
+			msg.Set(fd, v)
+
+			return nil
+		}
+
+		list := msg.Mutable(fd).List()
+		for _, value := range values {
+			v, err := parseFieldValue(fd, value, list.NewElement())
+			if err != nil {
+				return err
+			}
+
+			list.Append(v)
+		}
+	}
+
+	return nil
+}
+
+// parseFieldValue parses the value of a path variable or query parameter. Messages are
+// parsed into the message of zero.
+func parseFieldValue(fd protoreflect.FieldDescriptor, value string, zero protoreflect.Value) (protoreflect.Value, error) {
+	var (
+		v   interface{}
+		err error
+	)
+
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		v = value
+	case protoreflect.BoolKind:
+		v, err = strconv.ParseBool(value)
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		var n int64
+		n, err = strconv.ParseInt(value, 10, 32)
+		v = int32(n)
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		v, err = strconv.ParseInt(value, 10, 64)
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		var n uint64
+		n, err = strconv.ParseUint(value, 10, 32)
+		v = uint32(n)
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		v, err = strconv.ParseUint(value, 10, 64)
+	case protoreflect.FloatKind:
+		var f float64
+		f, err = strconv.ParseFloat(value, 32)
+		v = float32(f)
+	case protoreflect.DoubleKind:
+		v, err = strconv.ParseFloat(value, 64)
+	case protoreflect.BytesKind:
+		v, err = decodeBytesParameter(value)
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByName(protoreflect.Name(value)); ev != nil {
+			return protoreflect.ValueOfEnum(ev.Number()), nil
+		}
+
+		var n int64
+		n, err = strconv.ParseInt(value, 10, 32)
+		v = protoreflect.EnumNumber(n)
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		// well known types, such as timestamps and wrappers, have a JSON string form
+		m := zero.Message()
+		err = protojson.Unmarshal([]byte(strconv.Quote(value)), m.Interface())
+		v = m
+	default:
+		return protoreflect.Value{}, status.Errorf(codes.InvalidArgument, "unsupported type for field %q", fd.FullName())
+	}
+
+	if err != nil {
+		return protoreflect.Value{}, status.Errorf(codes.InvalidArgument, "invalid value %q for field %q: %v", value, fd.Name(), err)
+	}
+
+	return protoreflect.ValueOf(v), nil
+}
+
+// decodeBytesParameter decodes base64, in either the standard or URL alphabet, with optional padding.
+func decodeBytesParameter(value string) ([]byte, error) {
+	value = strings.TrimRight(value, "=")
+
+	if strings.ContainsAny(value, "-_") {
+		return base64.RawURLEncoding.DecodeString(value)
+	}
+
+	return base64.RawStdEncoding.DecodeString(value)
+}
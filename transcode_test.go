@@ -0,0 +1,168 @@
+package simplegrpc_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bakins/simplegrpc"
+	"github.com/bakins/simplegrpc/codes"
+	"github.com/bakins/simplegrpc/status"
+	"github.com/bakins/simplegrpc/testdata/transcode"
+)
+
+type lookupServer struct{}
+
+func (s *lookupServer) GetFeature(ctx context.Context, point *transcode.Point) (*transcode.Feature, error) {
+	return &transcode.Feature{
+		Name:     "testing",
+		Location: point,
+	}, nil
+}
+
+func TestTranscode(t *testing.T) {
+	var methods []string
+
+	h := simplegrpc.NewHandler(simplegrpc.WithUnaryInterceptor(func(ctx context.Context, req interface{}, info *simplegrpc.UnaryServerInfo, handler simplegrpc.UnaryHandler) (interface{}, error) {
+		methods = append(methods, info.FullMethod)
+
+		if req.(*transcode.Point).Latitude == 0 {
+			return nil, status.Error(codes.NotFound, "no feature")
+		}
+
+		return handler(ctx, req)
+	}))
+	transcode.RegisterLookupSimpleServer(h, &lookupServer{})
+
+	svr := httptest.NewServer(h)
+	defer svr.Close()
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+		want   string
+	}{
+		{
+			name:   "path",
+			method: http.MethodGet,
+			path:   "/v1/features/100/1",
+			status: http.StatusOK,
+			want:   `{"name":"testing","location":{"latitude":100,"longitude":1}}`,
+		},
+		{
+			name:   "query",
+			method: http.MethodGet,
+			path:   "/v1/feature?latitude=100&longitude=1&cache=1",
+			status: http.StatusOK,
+			want:   `{"name":"testing","location":{"latitude":100,"longitude":1}}`,
+		},
+		{
+			name:   "body",
+			method: http.MethodPost,
+			path:   "/v1/features:lookup",
+			body:   `{"latitude":100,"longitude":1}`,
+			status: http.StatusOK,
+			want:   `{"name":"testing","location":{"latitude":100,"longitude":1}}`,
+		},
+		{
+			name:   "body field",
+			method: http.MethodPut,
+			path:   "/v1/features/1/latitude",
+			body:   `100`,
+			status: http.StatusOK,
+			want:   `{"latitude":100,"longitude":1}`,
+		},
+		{
+			name:   "invalid path variable",
+			method: http.MethodGet,
+			path:   "/v1/features/north/1",
+			status: http.StatusBadRequest,
+			want:   `{"code":"invalid_argument"}`,
+		},
+		{
+			name:   "invalid body",
+			method: http.MethodPost,
+			path:   "/v1/features:lookup",
+			body:   `{`,
+			status: http.StatusBadRequest,
+			want:   `{"code":"invalid_argument"}`,
+		},
+		{
+			name:   "error",
+			method: http.MethodGet,
+			path:   "/v1/features/0/1",
+			status: http.StatusNotFound,
+			want:   `{"code":"not_found","message":"no feature"}`,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			methods = nil
+
+			req, err := http.NewRequest(test.method, svr.URL+test.path, strings.NewReader(test.body))
+			require.NoError(t, err)
+
+			resp, err := svr.Client().Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			require.Equal(t, test.status, resp.StatusCode)
+			require.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+			body, err := ioutil.ReadAll(resp.Body)
+			require.NoError(t, err)
+
+			if test.status != http.StatusOK {
+				// only compare the code, as messages of decoding errors may change
+				var got, want map[string]interface{}
+				require.NoError(t, json.Unmarshal(body, &got))
+				require.NoError(t, json.Unmarshal([]byte(test.want), &want))
+
+				require.Equal(t, want["code"], got["code"])
+
+				if message, ok := want["message"]; ok {
+					require.Equal(t, message, got["message"])
+				}
+
+				return
+			}
+
+			require.JSONEq(t, test.want, string(body))
+			require.Equal(t, []string{"/simplegrpc.testdata.transcode.Lookup/GetFeature"}, methods)
+		})
+	}
+}
+
+func TestTranscodeStreamInterceptor(t *testing.T) {
+	var info *simplegrpc.StreamServerInfo
+
+	h := simplegrpc.NewHandler(simplegrpc.WithStreamInterceptor(func(srv interface{}, ss simplegrpc.ServerStream, i *simplegrpc.StreamServerInfo, handler simplegrpc.StreamHandler) error {
+		info = i
+		return handler(srv, ss)
+	}))
+	transcode.RegisterLookupSimpleServer(h, &lookupServer{})
+
+	svr := httptest.NewServer(h)
+	defer svr.Close()
+
+	resp, err := svr.Client().Get(svr.URL + "/v1/features/100/1")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NotNil(t, info)
+	require.Equal(t, "/simplegrpc.testdata.transcode.Lookup/GetFeature", info.FullMethod)
+	require.False(t, info.IsClientStream)
+	require.False(t, info.IsServerStream)
+}
@@ -0,0 +1,172 @@
+package simplegrpc
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// gRPC-Web is described in https://github.com/grpc/grpc/blob/master/doc/PROTOCOL-WEB.md
+// It does not use HTTP trailers, so it works with HTTP/1.1 and browsers.
+
+const (
+	grpcWebContentType     = "application/grpc-web"
+	grpcWebTextContentType = "application/grpc-web-text"
+)
+
+// headers that browsers must be allowed to read from gRPC-Web responses.
+var grpcWebExposeHeaders = []string{"grpc-status", "grpc-message", "grpc-status-details-bin", "grpc-encoding", "grpc-accept-encoding"}
+
+// writeWebTrailer writes trailer to the body as a trailer frame.
+func writeWebTrailer(w io.Writer, trailer http.Header) error {
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	var header [frameHeaderLen]byte
+	_, _ = buf.Write(header[:])
+
+	keys := make([]string, 0, len(trailer))
+	for k := range trailer {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		for _, v := range trailer[k] {
+			_, _ = buf.WriteString(strings.ToLower(k))
+			_, _ = buf.WriteString(": ")
+			_, _ = buf.WriteString(v)
+			_, _ = buf.WriteString("\r\n")
+		}
+	}
+
+	header[0] = flagTrailer
+	binary.BigEndian.PutUint32(header[1:], uint32(buf.Len()-frameHeaderLen))
+	copy(buf.Bytes(), header[:])
+
+	_, err := w.Write(buf.Bytes())
+
+	return err
+}
+
+// base64ResponseWriter base64 encodes the body for grpc-web-text. Each write is
+// encoded separately, so each frame ends with any padding it needs.
+type base64ResponseWriter struct {
+	http.ResponseWriter
+}
+
+func (b base64ResponseWriter) Write(p []byte) (int, error) {
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	n := base64.StdEncoding.EncodedLen(len(p))
+	buf.Grow(n)
+
+	out := buf.Bytes()[:n]
+	base64.StdEncoding.Encode(out, p)
+
+	if _, err := b.ResponseWriter.Write(out); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+func (b base64ResponseWriter) Flush() {
+	if f, ok := b.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// base64Reader decodes a grpc-web-text body. Each four byte quantum is decoded
+// on its own, so the body may be made of separately padded chunks.
+type base64Reader struct {
+	reader io.Reader
+	// in holds encoded bytes that are not yet a complete quantum.
+	in  []byte
+	out []byte
+	buf [4096]byte
+	err error
+}
+
+func newBase64Reader(r io.Reader) *base64Reader {
+	return &base64Reader{reader: r}
+}
+
+func (b *base64Reader) Read(p []byte) (int, error) {
+	for len(b.out) == 0 {
+		if b.err != nil {
+			if b.err == io.EOF && len(b.in) > 0 {
+				return 0, io.ErrUnexpectedEOF
+			}
+
+			return 0, b.err
+		}
+
+		n, err := b.reader.Read(b.buf[:])
+		b.err = err
+
+		b.in = append(b.in, b.buf[:n]...)
+
+		quanta := len(b.in) / 4 * 4
+
+		var out []byte
+		for i := 0; i < quanta; i += 4 {
+			var decoded [3]byte
+
+			m, err := base64.StdEncoding.Decode(decoded[:], b.in[i:i+4])
+			if err != nil {
+				b.err = err
+				break
+			}
+
+			out = append(out, decoded[:m]...)
+		}
+
+		b.out = out
+		b.in = append(b.in[:0], b.in[quanta:]...)
+	}
+
+	n := copy(p, b.out)
+	b.out = b.out[n:]
+
+	return n, nil
+}
+
+// isPreflight returns true for CORS preflight requests.
+func isPreflight(r *http.Request) bool {
+	return r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+}
+
+// servePreflight responds to a CORS preflight request.
+func (h *Handler) servePreflight(w http.ResponseWriter, r *http.Request) {
+	if !h.setCORSHeaders(w, r) {
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Methods", http.MethodPost)
+	if headers := r.Header.Get("Access-Control-Request-Headers"); headers != "" {
+		w.Header().Set("Access-Control-Allow-Headers", headers)
+	}
+	w.Header().Set("Access-Control-Max-Age", "600")
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// setCORSHeaders allows the request's origin if it is allowed by the handler.
+func (h *Handler) setCORSHeaders(w http.ResponseWriter, r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || h.allowOrigin == nil || !h.allowOrigin(origin) {
+		return false
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", origin)
+	w.Header().Add("Vary", "Origin")
+
+	return true
+}
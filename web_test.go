@@ -0,0 +1,81 @@
+package simplegrpc
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseContentType(t *testing.T) {
+	tests := []struct {
+		contentType string
+		protocol    protocol
+		subType     string
+	}{
+		{"application/grpc", protocolGRPC, "proto"},
+		{"application/grpc+json", protocolGRPC, "json"},
+		{"application/grpc-web", protocolGRPCWeb, "proto"},
+		{"application/grpc-web+proto", protocolGRPCWeb, "proto"},
+		{"application/grpc-web-text", protocolGRPCWebText, "proto"},
+		{"application/grpc-web-text+json", protocolGRPCWebText, "json"},
+		{"application/grpc-webby", protocolGRPC, ""},
+		{"application/json", protocolConnectUnary, "json"},
+		{"application/json; charset=utf-8", protocolConnectUnary, "json"},
+		{"application/proto", protocolConnectUnary, "proto"},
+		{"application/connect+proto", protocolConnectStream, "proto"},
+		{"application/grpcx", protocolGRPC, ""},
+	}
+
+	for _, test := range tests {
+		p, subType := parseContentType(test.contentType)
+		require.Equal(t, test.subType, subType, test.contentType)
+
+		if subType != "" {
+			require.Equal(t, test.protocol, p, test.contentType)
+		}
+	}
+}
+
+func TestBase64Reader(t *testing.T) {
+	// separately padded chunks, as sent by some clients
+	encoded := base64.StdEncoding.EncodeToString([]byte("a")) +
+		base64.StdEncoding.EncodeToString([]byte("bc")) +
+		base64.StdEncoding.EncodeToString([]byte("def"))
+
+	data, err := ioutil.ReadAll(newBase64Reader(iotest.OneByteReader(bytes.NewReader([]byte(encoded)))))
+	require.NoError(t, err)
+	require.Equal(t, "abcdef", string(data))
+
+	_, err = ioutil.ReadAll(newBase64Reader(bytes.NewReader([]byte("YWJj!"))))
+	require.Error(t, err)
+
+	_, err = ioutil.ReadAll(newBase64Reader(bytes.NewReader([]byte("YWJjZ"))))
+	require.Equal(t, io.ErrUnexpectedEOF, err)
+}
+
+func TestWriteWebTrailer(t *testing.T) {
+	trailer := make(http.Header)
+	trailer.Set("Grpc-Status", "0")
+	trailer.Set("Grpc-Message", "OK")
+
+	var buf bytes.Buffer
+	require.NoError(t, writeWebTrailer(&buf, trailer))
+
+	payload := "grpc-message: OK\r\ngrpc-status: 0\r\n"
+	expected := append([]byte{0x80, 0, 0, 0, byte(len(payload))}, payload...)
+	require.Equal(t, expected, buf.Bytes())
+}
+
+func TestWebTrailerMessage(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, writeWebTrailer(&buf, statusHeader(errors.New("bad\r\ngrpc-status: 0"))))
+
+	require.Equal(t, "grpc-message: bad%0D%0Agrpc-status: 0\r\ngrpc-status: 2\r\n", buf.String()[5:])
+}